@@ -34,7 +34,7 @@ func Format(err error) string {
 		user = um.UserMessage()
 	}
 
-	if !debugEnabled() {
+	if !DebugEnabled() {
 		return user
 	}
 	return user + "\n\nDebug:\n" + unwrapChain(err)
@@ -57,7 +57,8 @@ func (e friendlyError) Unwrap() error {
 	return e.err
 }
 
-func debugEnabled() bool {
+// DebugEnabled reports whether P_DEBUG asks for extra diagnostics.
+func DebugEnabled() bool {
 	v := strings.TrimSpace(strings.ToLower(os.Getenv(envDebug)))
 	return v == "1" || v == "true" || v == "yes" || v == "on"
 }
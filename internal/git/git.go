@@ -0,0 +1,213 @@
+// Package git answers the questions p asks about the repository a
+// directory belongs to. It runs the git command line.
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotRepository is returned for a directory outside any repository.
+var ErrNotRepository = errors.New("not a git repository")
+
+// run runs git in dir and returns its trimmed output.
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "not a git repository") {
+			return "", ErrNotRepository
+		}
+		if msg == "" {
+			return "", fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// TopLevel returns the root of the working tree containing dir. In a linked
+// worktree this is the worktree's own directory.
+func TopLevel(dir string) (string, error) {
+	return run(dir, "rev-parse", "--show-toplevel")
+}
+
+// RepoName returns the repository's name: the directory of its main working
+// tree, or of the bare repository without .git, for every worktree.
+func RepoName(dir string) (string, error) {
+	common, err := run(dir, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	if filepath.Base(common) == ".git" {
+		common = filepath.Dir(common)
+	}
+	return strings.TrimSuffix(filepath.Base(common), ".git"), nil
+}
+
+// Branch returns the checked-out branch, or the short commit hash when HEAD
+// is detached.
+func Branch(dir string) (string, error) {
+	branch, err := run(dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err == nil {
+		return branch, nil
+	}
+	if errors.Is(err, ErrNotRepository) {
+		return "", err
+	}
+	return run(dir, "rev-parse", "--short", "HEAD")
+}
+
+// WorktreeName returns the name of the working tree containing dir: the
+// base name of its top level.
+func WorktreeName(dir string) (string, error) {
+	top, err := TopLevel(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(top), nil
+}
+
+// MainWorktree returns the main working tree of the repository containing
+// dir, which is the same from any of its linked worktrees.
+func MainWorktree(dir string) (string, error) {
+	common, err := run(dir, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	if filepath.Base(common) != ".git" {
+		return "", fmt.Errorf("%s is a bare repository", common)
+	}
+	return filepath.Dir(common), nil
+}
+
+// Worktree is a working tree of a repository. Branch is empty when its
+// HEAD is detached.
+type Worktree struct {
+	Path   string
+	Branch string
+}
+
+// Worktrees lists the working trees of the repository containing dir, the
+// main one first.
+func Worktrees(dir string) ([]Worktree, error) {
+	out, err := run(dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	var worktrees []Worktree
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "worktree "):
+			worktrees = append(worktrees, Worktree{Path: strings.TrimPrefix(line, "worktree ")})
+		case strings.HasPrefix(line, "branch ") && len(worktrees) > 0:
+			worktrees[len(worktrees)-1].Branch = strings.TrimPrefix(line, "branch refs/heads/")
+		}
+	}
+	return worktrees, nil
+}
+
+// FindWorktree returns the worktree with branch checked out, if any.
+func FindWorktree(dir, branch string) (Worktree, bool, error) {
+	worktrees, err := Worktrees(dir)
+	if err != nil {
+		return Worktree{}, false, err
+	}
+	for _, w := range worktrees {
+		if w.Branch == branch {
+			return w, true, nil
+		}
+	}
+	return Worktree{}, false, nil
+}
+
+// envWorktreeDir overrides where WorktreeDir puts worktrees.
+const envWorktreeDir = "P_WORKTREE_DIR"
+
+// WorktreeDir is where a new worktree for branch goes: beside the main
+// worktree in <repo>.worktrees, or under P_WORKTREE_DIR/<repo>. Slashes in
+// the branch name become dashes.
+func WorktreeDir(mainWorktree, repo, branch string) string {
+	name := strings.ReplaceAll(branch, "/", "-")
+	if base := strings.TrimSpace(os.Getenv(envWorktreeDir)); base != "" {
+		if resolved, err := absPath(base); err == nil {
+			base = resolved
+		}
+		return filepath.Join(base, repo, name)
+	}
+	return filepath.Join(filepath.Dir(mainWorktree), repo+".worktrees", name)
+}
+
+// AddWorktree checks branch out in a new worktree at path. An existing
+// local branch is used as is; a branch found on exactly one remote is
+// created to track it; otherwise a new branch starts from HEAD. A branch
+// found on several remotes is an error, as git can't tell which to track.
+func AddWorktree(dir, path, branch string) error {
+	args := []string{"worktree", "add", "--quiet", path, branch}
+	if !hasBranch(dir, branch) {
+		remotes, err := remotesWithBranch(dir, branch)
+		if err != nil {
+			return err
+		}
+		switch len(remotes) {
+		case 0:
+			args = []string{"worktree", "add", "--quiet", "-b", branch, path}
+		case 1:
+		default:
+			return fmt.Errorf("branch %s is on several remotes (%s); pick one with git branch --track %s <remote>/%s",
+				branch, strings.Join(remotes, ", "), branch, branch)
+		}
+	}
+	_, err := run(dir, args...)
+	return err
+}
+
+// RemoveWorktree removes the worktree at path. Without force, git refuses
+// to remove a worktree with uncommitted changes.
+func RemoveWorktree(dir, path string, force bool) error {
+	args := []string{"worktree", "remove", path}
+	if force {
+		args = []string{"worktree", "remove", "--force", path}
+	}
+	_, err := run(dir, args...)
+	return err
+}
+
+func hasBranch(dir, branch string) bool {
+	_, err := run(dir, "show-ref", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// remotesWithBranch lists the remotes that have branch.
+func remotesWithBranch(dir, branch string) ([]string, error) {
+	out, err := run(dir, "remote")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	var found []string
+	for _, remote := range strings.Split(out, "\n") {
+		if _, err := run(dir, "show-ref", "--verify", "--quiet", "refs/remotes/"+remote+"/"+branch); err == nil {
+			found = append(found, remote)
+		}
+	}
+	return found, nil
+}
+
+// absPath makes a configured directory absolute, expanding a leading ~.
+func absPath(path string) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	return filepath.Abs(path)
+}
@@ -0,0 +1,166 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newRepo creates a repository with one commit on main.
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := filepath.Join(t.TempDir(), "proj")
+	gitCmd(t, "", "init", "-q", "-b", "main", dir)
+	gitCmd(t, dir, "-c", "user.name=p", "-c", "user.email=p@example.com", "commit", "-q", "--allow-empty", "-m", "init")
+	return dir
+}
+
+func gitCmd(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	repo := newRepo(t)
+	sub := filepath.Join(repo, "internal", "ui")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	wt := filepath.Join(filepath.Dir(repo), "proj-feature")
+	gitCmd(t, repo, "worktree", "add", "-q", "-b", "feature/login", wt)
+
+	tests := []struct {
+		dir                    string
+		repo, branch, worktree string
+	}{
+		{sub, "proj", "main", "proj"},
+		{wt, "proj", "feature/login", "proj-feature"},
+	}
+	for _, tt := range tests {
+		if got, err := RepoName(tt.dir); err != nil || got != tt.repo {
+			t.Errorf("RepoName(%s) = %q, %v; want %q", tt.dir, got, err, tt.repo)
+		}
+		if got, err := Branch(tt.dir); err != nil || got != tt.branch {
+			t.Errorf("Branch(%s) = %q, %v; want %q", tt.dir, got, err, tt.branch)
+		}
+		if got, err := WorktreeName(tt.dir); err != nil || got != tt.worktree {
+			t.Errorf("WorktreeName(%s) = %q, %v; want %q", tt.dir, got, err, tt.worktree)
+		}
+	}
+}
+
+func TestBranchWhenDetached(t *testing.T) {
+	repo := newRepo(t)
+	gitCmd(t, repo, "checkout", "-q", "--detach")
+	got, err := Branch(repo)
+	if err != nil || len(got) < 7 {
+		t.Fatalf("expected a short hash, got %q, %v", got, err)
+	}
+}
+
+func TestNotRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+	if _, err := RepoName(dir); !errors.Is(err, ErrNotRepository) {
+		t.Fatalf("expected ErrNotRepository, got %v", err)
+	}
+	if _, err := Branch(dir); !errors.Is(err, ErrNotRepository) {
+		t.Fatalf("expected ErrNotRepository, got %v", err)
+	}
+}
+
+func TestWorktreeLifecycle(t *testing.T) {
+	repo := newRepo(t)
+	gitCmd(t, repo, "branch", "existing")
+	if main, err := MainWorktree(repo); err != nil || main != repo {
+		t.Fatalf("MainWorktree = %q, %v", main, err)
+	}
+
+	for _, branch := range []string{"existing", "feature/new"} {
+		path := filepath.Join(filepath.Dir(repo), "wt-"+filepath.Base(branch))
+		if err := AddWorktree(repo, path, branch); err != nil {
+			t.Fatalf("add %s: %v", branch, err)
+		}
+		w, found, err := FindWorktree(path, branch)
+		if err != nil || !found || w.Path != path {
+			t.Fatalf("find %s: %+v, %v, %v", branch, w, found, err)
+		}
+		if main, err := MainWorktree(path); err != nil || main != repo {
+			t.Fatalf("MainWorktree from %s = %q, %v", path, main, err)
+		}
+	}
+
+	worktrees, err := Worktrees(repo)
+	if err != nil || len(worktrees) != 3 || worktrees[0].Path != repo || worktrees[0].Branch != "main" {
+		t.Fatalf("worktrees: %+v, %v", worktrees, err)
+	}
+
+	path := filepath.Join(filepath.Dir(repo), "wt-existing")
+	if err := os.WriteFile(filepath.Join(path, "dirty"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveWorktree(repo, path, false); err == nil {
+		t.Fatalf("expected git to refuse removing a dirty worktree")
+	}
+	if err := RemoveWorktree(repo, path, true); err != nil {
+		t.Fatalf("forced remove: %v", err)
+	}
+	if _, found, _ := FindWorktree(repo, "existing"); found {
+		t.Fatalf("worktree still listed after removal")
+	}
+}
+
+func TestWorktreeDir(t *testing.T) {
+	t.Setenv("P_WORKTREE_DIR", "")
+	if got := WorktreeDir("/src/shop", "shop", "feature/login"); got != "/src/shop.worktrees/feature-login" {
+		t.Fatalf("default: %q", got)
+	}
+	t.Setenv("P_WORKTREE_DIR", "/wt")
+	if got := WorktreeDir("/src/shop", "shop", "fix"); got != "/wt/shop/fix" {
+		t.Fatalf("P_WORKTREE_DIR: %q", got)
+	}
+}
+
+func TestAddWorktreeTracksRemoteBranch(t *testing.T) {
+	upstream := newRepo(t)
+	gitCmd(t, upstream, "branch", "remote-only")
+	clone := filepath.Join(t.TempDir(), "clone")
+	gitCmd(t, "", "clone", "-q", upstream, clone)
+
+	path := filepath.Join(filepath.Dir(clone), "wt")
+	if err := AddWorktree(clone, path, "remote-only"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	out, err := run(path, "rev-parse", "--abbrev-ref", "remote-only@{upstream}")
+	if err != nil || out != "origin/remote-only" {
+		t.Fatalf("upstream = %q, %v", out, err)
+	}
+}
+
+func TestAddWorktreeRejectsBranchOnSeveralRemotes(t *testing.T) {
+	upstream := newRepo(t)
+	gitCmd(t, upstream, "branch", "shared")
+	clone := filepath.Join(t.TempDir(), "clone")
+	gitCmd(t, "", "clone", "-q", upstream, clone)
+	gitCmd(t, clone, "remote", "add", "fork", upstream)
+	gitCmd(t, clone, "fetch", "-q", "fork")
+
+	err := AddWorktree(clone, filepath.Join(filepath.Dir(clone), "wt"), "shared")
+	if err == nil || !strings.Contains(err.Error(), "several remotes (fork, origin)") {
+		t.Fatalf("expected an ambiguity error naming both remotes, got %v", err)
+	}
+}
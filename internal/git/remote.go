@@ -0,0 +1,131 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Remote is a repository to clone. Path is its location on Host, without a
+// trailing .git, e.g. wilmoore/p on github.com.
+type Remote struct {
+	URL  string
+	Host string
+	Path string
+	// Shorthand is set when the remote was given as owner/repo, which may
+	// also be a mistyped path.
+	Shorthand bool
+}
+
+// shorthandHost is where owner/repo shorthands are cloned from.
+const shorthandHost = "github.com"
+
+var (
+	// scpPattern matches scp-like addresses: user@host:owner/repo.git.
+	scpPattern = regexp.MustCompile(`^[\w.-]+@([\w.-]+):([^/].*)$`)
+	// shorthandPattern matches owner/repo.
+	shorthandPattern = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+)
+
+// remoteSchemes are the URL schemes git clones from.
+var remoteSchemes = []string{"https", "http", "ssh", "git", "file"}
+
+// ParseRemote recognises a clone URL, an scp-like address or, when
+// shorthand is set, an owner/repo shorthand for GitHub. It reports false
+// for anything else, such as a local path.
+func ParseRemote(s string, shorthand bool) (Remote, bool) {
+	if m := scpPattern.FindStringSubmatch(s); m != nil {
+		return newRemote(s, m[1], m[2])
+	}
+	if scheme, _, ok := strings.Cut(s, "://"); ok {
+		u, err := url.Parse(s)
+		if err != nil || !slices.Contains(remoteSchemes, scheme) {
+			return Remote{}, false
+		}
+		host := u.Hostname()
+		if u.Scheme == "file" && host == "" {
+			host = "local"
+		}
+		return newRemote(s, host, u.Path)
+	}
+	if shorthand && shorthandPattern.MatchString(s) && !strings.HasPrefix(s, ".") {
+		r, ok := newRemote("https://"+shorthandHost+"/"+strings.TrimSuffix(s, ".git")+".git", shorthandHost, s)
+		r.Shorthand = ok
+		return r, ok
+	}
+	return Remote{}, false
+}
+
+// newRemote checks that host and repoPath stay inside the directory clones
+// are placed in, since both become path components.
+func newRemote(rawURL, host, repoPath string) (Remote, bool) {
+	repoPath = strings.TrimSuffix(strings.Trim(path.Clean("/"+repoPath), "/"), ".git")
+	if host == "" || host == "." || host == ".." || strings.ContainsAny(host, `/\`) {
+		return Remote{}, false
+	}
+	if repoPath == "" || repoPath == "." {
+		return Remote{}, false
+	}
+	return Remote{URL: rawURL, Host: host, Path: repoPath}, true
+}
+
+// envSrcRoot is where CloneDir places clones.
+const envSrcRoot = "P_SRC_ROOT"
+
+// CloneDir is where r is cloned: <host>/<path> under P_SRC_ROOT, by default
+// ~/src.
+func CloneDir(r Remote) (string, error) {
+	root := strings.TrimSpace(os.Getenv(envSrcRoot))
+	if root == "" {
+		root = "~/src"
+	}
+	root, err := absPath(root)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, r.Host, filepath.FromSlash(r.Path)), nil
+}
+
+// Clone clones url into dir, which must not exist, creating its parents.
+// Parents it created are removed again if the clone fails.
+func Clone(url, dir string) error {
+	parent := filepath.Dir(dir)
+	top := parent
+	for {
+		up := filepath.Dir(top)
+		if _, err := os.Stat(up); err == nil || up == top {
+			break
+		}
+		top = up
+	}
+	_, statErr := os.Stat(parent)
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return err
+	}
+	err := clone(url, dir)
+	if err != nil && statErr != nil {
+		for d := parent; ; d = filepath.Dir(d) {
+			if os.Remove(d) != nil || d == top {
+				break
+			}
+		}
+	}
+	return err
+}
+
+func clone(url, dir string) error {
+	cmd := exec.Command("git", "clone", "--quiet", "--", url, dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("failed to clone %s: %s", url, msg)
+		}
+		return fmt.Errorf("failed to clone %s: %w", url, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		input     string
+		shorthand bool
+		want      Remote
+		ok        bool
+	}{
+		{"https://github.com/wilmoore/p.git", false, Remote{"https://github.com/wilmoore/p.git", "github.com", "wilmoore/p", false}, true},
+		{"https://gitlab.com/group/sub/repo", false, Remote{"https://gitlab.com/group/sub/repo", "gitlab.com", "group/sub/repo", false}, true},
+		{"git@github.com:wilmoore/p.git", false, Remote{"git@github.com:wilmoore/p.git", "github.com", "wilmoore/p", false}, true},
+		{"ssh://git@example.com:2222/team/app.git", false, Remote{"ssh://git@example.com:2222/team/app.git", "example.com", "team/app", false}, true},
+		{"file:///srv/git/app.git", false, Remote{"file:///srv/git/app.git", "local", "srv/git/app", false}, true},
+		{"wilmoore/p", true, Remote{"https://github.com/wilmoore/p.git", "github.com", "wilmoore/p", true}, true},
+		{"wilmoore/p", false, Remote{}, false},
+		{"./p", true, Remote{}, false},
+		{"/tmp/p", true, Remote{}, false},
+		{"../a", true, Remote{}, false},
+		{"p", true, Remote{}, false},
+		{"ftp://example.com/a/b", false, Remote{}, false},
+		{"https://github.com/", false, Remote{}, false},
+		{"git@..:x/y", false, Remote{}, false},
+		{"git@.:x/y", false, Remote{}, false},
+		{"ssh://../x/y", false, Remote{}, false},
+		{"https://a%2Fb/x/y", false, Remote{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRemote(tt.input, tt.shorthand)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseRemote(%q, %v) = %+v, %v; want %+v, %v", tt.input, tt.shorthand, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	src := newRepo(t)
+	bare := filepath.Join(t.TempDir(), "app.git")
+	gitCmd(t, "", "clone", "-q", "--bare", src, bare)
+
+	dir := filepath.Join(t.TempDir(), "app")
+	if err := Clone("file://"+bare, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		t.Errorf("clone has no .git: %v", err)
+	}
+
+	missing := "file://" + filepath.Join(t.TempDir(), "missing.git")
+	err := Clone(missing, filepath.Join(t.TempDir(), "missing"))
+	if err == nil || !strings.Contains(err.Error(), "failed to clone "+missing) {
+		t.Errorf("Clone(missing) = %v", err)
+	}
+}
+
+func TestCloneDir(t *testing.T) {
+	remote := Remote{URL: "https://gitlab.com/team/app.git", Host: "gitlab.com", Path: "team/app"}
+	t.Setenv("P_SRC_ROOT", "/code")
+	if got, err := CloneDir(remote); err != nil || got != "/code/gitlab.com/team/app" {
+		t.Fatalf("P_SRC_ROOT: %q, %v", got, err)
+	}
+	t.Setenv("P_SRC_ROOT", "")
+	t.Setenv("HOME", "/home/me")
+	if got, err := CloneDir(remote); err != nil || got != "/home/me/src/gitlab.com/team/app" {
+		t.Fatalf("default: %q, %v", got, err)
+	}
+}
+
+func TestCloneRemovesNewParentsOnFailure(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("P_SRC_ROOT", root)
+	remote := Remote{URL: "file://" + filepath.Join(t.TempDir(), "missing.git"), Host: "local", Path: "team/missing"}
+	dir, _ := CloneDir(remote)
+
+	if err := Clone(remote.URL, dir); err == nil {
+		t.Fatalf("expected the clone to fail")
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Fatalf("expected the new directories removed, got %v", entries)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Fatalf("expected P_SRC_ROOT kept: %v", err)
+	}
+}
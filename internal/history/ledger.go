@@ -30,6 +30,9 @@ type Entry struct {
 	SessionName string    `json:"sessionName"`
 	InvokeDir   string    `json:"invokeDir"`
 	TargetDir   string    `json:"targetDir"`
+	// Server is the tmux socket name or path the session is on, empty for
+	// the default server.
+	Server string `json:"server,omitempty"`
 }
 
 const maxEntries = 200
@@ -3,8 +3,44 @@ package i18n
 const (
 	MsgNoSessionHistoryYet = "No session history yet."
 
+	MsgSavedTemplateFmt = "Saved %s\n"
+
+	MsgPlanSessionFmt   = "session:   %s\n"
+	MsgPlanDirectoryFmt = "directory: %s\n"
+	MsgPlanLayoutFmt    = "layout:    %s\n"
+	MsgPlanEnvFmt       = "env:       %s=%s\n"
+	MsgPlanPaneFmt      = "    %s: %s\n"
+	MsgPlanShell        = "(shell)"
+	MsgPlanCloneFmt     = "clone:     %s into %s\n"
+
+	MsgSavedSnapshotFmt         = "Saved %d sessions to %s\n"
+	MsgSessionRestoredFmt       = "%s: restored\n"
+	MsgSessionAlreadyRunningFmt = "%s: already running\n"
+
+	MsgRemovedWorktreeFmt       = "Removed worktree %s\n"
+	MsgKilledSessionFmt         = "Killed session %s\n"
+	MsgKillingCurrentSessionFmt = "Killing session %s\n"
+
+	MsgSessionOpenElsewhereFmt = "Session %q is already open in %s.\n"
+	MsgSessionNamePromptFmt    = "Name for %s [%s]: "
+	MsgSessionNameTakenFmt     = "%q is taken.\n"
+
+	MsgConfirmCloneFmt       = "Clone %s into %s? [y/N] "
+	MsgDetectedLayoutFmt     = "%s (detected layout)"
+	MsgTemplateWantsToRunFmt = "%s wants to run:\n"
+	MsgTemplateEnvFmt        = "  env %s=%s\n"
+	MsgTemplateCommandFmt    = "  %s: %s\n"
+	MsgTrustTemplatePrompt   = "Trust this template? [y/N] "
+
 	ErrHistoryMissingTargetDir = "history entry is missing target directory"
 	ErrNoTmuxSessionsAvailable = "no tmux sessions available"
 
+	ErrWorktreeOutsideRepoFmt       = "--worktree must be run inside a git repository: %w"
+	ErrWorktreeRemoveOutsideRepoFmt = "--worktree-remove must be run inside a git repository: %w"
+	ErrNoWorktreeForBranchFmt       = "no worktree has %s checked out"
+	ErrMainWorktreeCheckoutFmt      = "%s is checked out in the main worktree, which can't be removed"
+	ErrWorktreeHasChangesFmt        = "%w (use --force to discard its changes)"
+
 	WarnWriteHistoryFailedFmt = "warning: failed to write history: %v\n"
+	WarnUntrustedTemplateFmt  = "warning: %s is not trusted; starting without its commands (run p from a terminal to review it)\n"
 )
@@ -0,0 +1,72 @@
+// Package naming expands session naming templates such as {repo}-{branch}.
+package naming
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/wilmoore/p/internal/git"
+)
+
+// placeholders are the placeholders of a naming template.
+var placeholders = []string{"dir", "parent", "repo", "branch", "worktree"}
+
+// Expand fills in a naming template such as {repo}-{branch} for dir.
+// Outside a git repository {repo} and {worktree} are the directory's name
+// and {branch} is empty. Separators left dangling by empty placeholders
+// are trimmed.
+func Expand(tmpl, dir string) (string, error) {
+	var sb strings.Builder
+	rest := tmpl
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed { in name template %q", tmpl)
+		}
+		value, err := placeholderValue(rest[start+1:start+end], dir)
+		if err != nil {
+			return "", fmt.Errorf("name template %q: %w", tmpl, err)
+		}
+		sb.WriteString(rest[:start])
+		sb.WriteString(value)
+		rest = rest[start+end+1:]
+	}
+	name := strings.Trim(sb.String(), "-_/. ")
+	if name == "" {
+		name = filepath.Base(dir)
+	}
+	return name, nil
+}
+
+func placeholderValue(placeholder, dir string) (string, error) {
+	var value string
+	var err error
+	switch placeholder {
+	case "dir":
+		return filepath.Base(dir), nil
+	case "parent":
+		return filepath.Base(filepath.Dir(dir)), nil
+	case "repo":
+		value, err = git.RepoName(dir)
+	case "branch":
+		value, err = git.Branch(dir)
+	case "worktree":
+		value, err = git.WorktreeName(dir)
+	default:
+		return "", fmt.Errorf("unknown placeholder {%s} (use {%s})", placeholder, strings.Join(placeholders, "}, {"))
+	}
+	if errors.Is(err, git.ErrNotRepository) {
+		if placeholder == "branch" {
+			return "", nil
+		}
+		return filepath.Base(dir), nil
+	}
+	return value, err
+}
@@ -0,0 +1,52 @@
+package naming
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	repo := filepath.Join(root, "shop")
+	wt := filepath.Join(root, "shop-login")
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main", repo)
+	git("-C", repo, "-c", "user.name=p", "-c", "user.email=p@example.com", "commit", "-q", "--allow-empty", "-m", "init")
+	git("-C", repo, "worktree", "add", "-q", "-b", "login", wt)
+	plain := filepath.Join(root, "notes")
+	if err := os.Mkdir(plain, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CEILING_DIRECTORIES", root)
+
+	tests := []struct {
+		tmpl, dir, want string
+	}{
+		{"{repo}-{branch}", repo, "shop-main"},
+		{"{repo}-{branch}", wt, "shop-login"},
+		{"{worktree}", wt, "shop-login"},
+		{"{parent}/{dir}", repo, filepath.Base(root) + "/shop"},
+		{"{repo}-{branch}", plain, "notes"},
+		{"x-{branch}", plain, "x"},
+	}
+	for _, tt := range tests {
+		if got, err := Expand(tt.tmpl, tt.dir); err != nil || got != tt.want {
+			t.Errorf("Expand(%q, %s) = %q, %v; want %q", tt.tmpl, tt.dir, got, err, tt.want)
+		}
+	}
+	for _, tmpl := range []string{"{user}", "{repo"} {
+		if _, err := Expand(tmpl, repo); err == nil {
+			t.Errorf("expected error for %q", tmpl)
+		}
+	}
+}
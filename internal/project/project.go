@@ -0,0 +1,41 @@
+// Package project finds the root of the project a directory belongs to.
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// envMarkers replaces the default root markers.
+const envMarkers = "P_ROOT_MARKERS"
+
+// defaultMarkers are the files or directories marking a project root.
+var defaultMarkers = []string{".git", "go.mod", "package.json"}
+
+// Root returns the nearest directory at or above dir containing a
+// root marker, or dir when there is none. P_ROOT_MARKERS replaces the
+// default markers with a comma-separated list.
+func Root(dir string) string {
+	markers := defaultMarkers
+	if value := os.Getenv(envMarkers); strings.TrimSpace(value) != "" {
+		markers = nil
+		for _, m := range strings.Split(value, ",") {
+			if m = strings.TrimSpace(m); m != "" {
+				markers = append(markers, m)
+			}
+		}
+	}
+	for current := dir; ; {
+		for _, m := range markers {
+			if _, err := os.Stat(filepath.Join(current, m)); err == nil {
+				return current
+			}
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return dir
+		}
+		current = parent
+	}
+}
@@ -0,0 +1,40 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRoot(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "internal", "ui")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("P_ROOT_MARKERS", "")
+	if got := Root(sub); got != repo {
+		t.Fatalf("Root = %q, want %q", got, repo)
+	}
+
+	// The nearest marker wins.
+	if err := os.WriteFile(filepath.Join(repo, "internal", "go.mod"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := Root(sub); got != filepath.Join(repo, "internal") {
+		t.Fatalf("Root = %q, want the go.mod directory", got)
+	}
+
+	t.Setenv("P_ROOT_MARKERS", "WORKSPACE, .git")
+	if got := Root(sub); got != repo {
+		t.Fatalf("custom markers: Root = %q, want %q", got, repo)
+	}
+	t.Setenv("P_ROOT_MARKERS", "WORKSPACE")
+	if got := Root(sub); got != sub {
+		t.Fatalf("without a marker the directory is kept, got %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+// Result is what Restore did with one saved session.
+type Result struct {
+	Session string
+	// Skipped is set when a session of that name was already running.
+	Skipped bool
+	Err     error
+}
+
+// Restore rebuilds the snapshot's sessions on the selected server. Sessions
+// that are already running are left alone, so restoring twice is harmless.
+// A session whose directory is gone starts in the home directory.
+func Restore(snap *Snapshot) ([]Result, error) {
+	running := map[string]bool{}
+	sessions, err := tmux.ListSessions()
+	if err != nil && !tmux.IsNoServerError(err) {
+		return nil, err
+	}
+	for _, s := range sessions {
+		running[s.Name] = true
+	}
+
+	var results []Result
+	for _, s := range snap.Sessions {
+		if running[s.Name] {
+			results = append(results, Result{Session: s.Name, Skipped: true})
+			continue
+		}
+		err := restoreSession(s)
+		results = append(results, Result{Session: s.Name, Err: err})
+	}
+	return results, nil
+}
+
+func restoreSession(s Session) error {
+	dir := s.Dir
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		if dir, err = os.UserHomeDir(); err != nil {
+			return err
+		}
+	}
+	if _, err := tmux.CreateSession(s.Name, dir, s.Layout()); err != nil {
+		return err
+	}
+	return replayScrollback(s)
+}
+
+// replayScrollback prints each pane's saved scrollback in the restored pane.
+// The files are kept beside the snapshots, one set per session, and are
+// replaced on the next restore.
+func replayScrollback(s Session) error {
+	windows, err := tmux.ListWindows(s.Name)
+	if err != nil {
+		return err
+	}
+	base, err := Dir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(base, "scrollback", strings.ReplaceAll(s.Name, string(filepath.Separator), "_"))
+	for i, w := range s.Windows {
+		if i >= len(windows) {
+			break
+		}
+		panes, err := tmux.ListPanes(s.Name, windows[i].Index)
+		if err != nil {
+			return err
+		}
+		for j, p := range w.Panes {
+			if p.Scrollback == "" || j >= len(panes) {
+				continue
+			}
+			if err := os.MkdirAll(dir, 0o700); err != nil {
+				return err
+			}
+			path := filepath.Join(dir, fmt.Sprintf("%d.%d.txt", i, j))
+			if err := os.WriteFile(path, []byte(p.Scrollback), 0o600); err != nil {
+				return err
+			}
+			if err := tmux.ReplayFile(panes[j].Target(), path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+// Package snapshot saves every session on a tmux server to disk and
+// rebuilds them later, such as after a reboot.
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+// Snapshot is the sessions of a server at one point in time.
+type Snapshot struct {
+	Time     time.Time `json:"ts"`
+	Sessions []Session `json:"sessions"`
+}
+
+// Session is a saved session.
+type Session struct {
+	Name    string   `json:"name"`
+	Dir     string   `json:"dir"`
+	Focus   string   `json:"focus,omitempty"`
+	Windows []Window `json:"windows"`
+}
+
+// Window is a saved window. Layout is its layout string.
+type Window struct {
+	Name   string `json:"name"`
+	Layout string `json:"layout,omitempty"`
+	Panes  []Pane `json:"panes"`
+}
+
+// Pane is a saved pane, with its scrollback when that was requested.
+type Pane struct {
+	Dir        string `json:"dir"`
+	Scrollback string `json:"scrollback,omitempty"`
+}
+
+// maxSnapshots is how many snapshots are kept; older ones are removed.
+const maxSnapshots = 10
+
+const (
+	filePrefix = "snapshot-"
+	fileSuffix = ".json"
+	// timeFormat sorts lexically in time order.
+	timeFormat = "20060102T150405.000Z"
+)
+
+// Take captures every session on the selected server. With scrollback,
+// each pane's history is saved too.
+func Take(scrollback bool) (*Snapshot, error) {
+	sessions, err := tmux.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{Time: time.Now().UTC()}
+	for _, s := range sessions {
+		saved, err := takeSession(s.Name, scrollback)
+		if err != nil {
+			return nil, fmt.Errorf("session %q: %w", s.Name, err)
+		}
+		snap.Sessions = append(snap.Sessions, saved)
+	}
+	return snap, nil
+}
+
+func takeSession(name string, scrollback bool) (Session, error) {
+	dir, err := tmux.GetSessionPath(name)
+	if err != nil {
+		return Session{}, err
+	}
+	layout, err := tmux.CaptureLayout(name, false)
+	if err != nil {
+		return Session{}, err
+	}
+	windows, err := tmux.ListWindows(name)
+	if err != nil {
+		return Session{}, err
+	}
+	s := Session{Name: name, Dir: dir, Focus: layout.Focus}
+	for i, w := range layout.Windows {
+		saved := Window{Name: w.Name, Layout: w.Arrangement}
+		var panes []tmux.Pane
+		if scrollback && i < len(windows) {
+			if panes, err = tmux.ListPanes(name, windows[i].Index); err != nil {
+				return Session{}, err
+			}
+		}
+		for j, p := range w.Panes {
+			pane := Pane{Dir: p.Dir}
+			if j < len(panes) {
+				if pane.Scrollback, err = tmux.CapturePane(panes[j].Target()); err != nil {
+					return Session{}, err
+				}
+			}
+			saved.Panes = append(saved.Panes, pane)
+		}
+		s.Windows = append(s.Windows, saved)
+	}
+	return s, nil
+}
+
+// Layout returns the session's layout for tmux.CreateSession.
+func (s Session) Layout() tmux.Layout {
+	layout := tmux.Layout{Focus: s.Focus}
+	for _, w := range s.Windows {
+		spec := tmux.WindowSpec{Name: w.Name, Arrangement: w.Layout}
+		for _, p := range w.Panes {
+			spec.Panes = append(spec.Panes, tmux.PaneSpec{Dir: p.Dir})
+		}
+		layout.Windows = append(layout.Windows, spec)
+	}
+	return layout
+}
+
+// Save writes the snapshot to the snapshot directory and removes the
+// oldest snapshots beyond maxSnapshots. It returns the file written.
+func Save(snap *Snapshot) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, filePrefix+snap.Time.UTC().Format(timeFormat)+fileSuffix)
+	tmp, err := os.CreateTemp(dir, "snapshot-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return path, rotate(dir)
+}
+
+// rotate removes all but the newest maxSnapshots snapshots.
+func rotate(dir string) error {
+	files, err := list(dir)
+	if err != nil || len(files) <= maxSnapshots {
+		return err
+	}
+	for _, f := range files[:len(files)-maxSnapshots] {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// list returns the snapshot files in dir, oldest first.
+func list(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if name := e.Name(); strings.HasPrefix(name, filePrefix) && strings.HasSuffix(name, fileSuffix) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+// Latest returns the path of the newest snapshot.
+func Latest() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	files, err := list(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no snapshots in %s (take one with p --snapshot)", dir)
+	}
+	return files[len(files)-1], nil
+}
+
+// Load reads a snapshot file.
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &snap, nil
+}
+
+// Dir is where snapshots are kept: P_SNAPSHOT_DIR, or p/snapshots in the
+// XDG state directory.
+func Dir() (string, error) {
+	if override := os.Getenv("P_SNAPSHOT_DIR"); override != "" {
+		return override, nil
+	}
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine home directory: %w", err)
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "p", "snapshots"), nil
+}
@@ -0,0 +1,156 @@
+package snapshot
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+func TestSaveRotatesAndLoadsLatest(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("P_SNAPSHOT_DIR", dir)
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	var last string
+	for i := range maxSnapshots + 3 {
+		snap := &Snapshot{Time: start.Add(time.Duration(i) * time.Second), Sessions: []Session{{Name: "api"}}}
+		path, err := Save(snap)
+		if err != nil {
+			t.Fatalf("save: %v", err)
+		}
+		last = path
+	}
+	files, err := list(dir)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(files) != maxSnapshots {
+		t.Fatalf("expected %d snapshots after rotation, got %d", maxSnapshots, len(files))
+	}
+	if latest, err := Latest(); err != nil || latest != last {
+		t.Fatalf("latest = %q, %v; want %q", latest, err, last)
+	}
+	snap, err := Load(last)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !snap.Time.Equal(start.Add((maxSnapshots+2)*time.Second)) || snap.Sessions[0].Name != "api" {
+		t.Fatalf("loaded %+v", snap)
+	}
+}
+
+func TestLatestWithoutSnapshots(t *testing.T) {
+	t.Setenv("P_SNAPSHOT_DIR", filepath.Join(t.TempDir(), "missing"))
+	if _, err := Latest(); err == nil || !strings.Contains(err.Error(), "--snapshot") {
+		t.Fatalf("expected a hint to take a snapshot, got %v", err)
+	}
+}
+
+func TestDirFollowsXDGStateHome(t *testing.T) {
+	t.Setenv("P_SNAPSHOT_DIR", "")
+	t.Setenv("XDG_STATE_HOME", "/state")
+	if dir, err := Dir(); err != nil || dir != "/state/p/snapshots" {
+		t.Fatalf("dir = %q, %v", dir, err)
+	}
+}
+
+func TestSessionLayout(t *testing.T) {
+	s := Session{Name: "api", Focus: "logs", Windows: []Window{
+		{Name: "home", Panes: []Pane{{Dir: "/work/api"}}},
+		{Name: "logs", Layout: "tiled", Panes: []Pane{{Dir: "/var/log", Scrollback: "x\n"}, {Dir: "/tmp"}}},
+	}}
+	want := tmux.Layout{Focus: "logs", Windows: []tmux.WindowSpec{
+		{Name: "home", Panes: []tmux.PaneSpec{{Dir: "/work/api"}}},
+		{Name: "logs", Arrangement: "tiled", Panes: []tmux.PaneSpec{{Dir: "/var/log"}, {Dir: "/tmp"}}},
+	}}
+	if got := s.Layout(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("layout:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestIntegrationTakeAndRestore(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping tmux integration test in short mode")
+	}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux not installed")
+	}
+	socketDir, err := os.MkdirTemp("", "p-tmux-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TMUX", "")
+	t.Setenv("P_SNAPSHOT_DIR", t.TempDir())
+	socket := filepath.Join(socketDir, "s")
+	restore := tmux.SetServer(tmux.Server{Socket: socket})
+	t.Cleanup(func() {
+		exec.Command("tmux", "-S", socket, "kill-server").Run()
+		restore()
+		os.RemoveAll(socketDir)
+	})
+
+	dir, other := t.TempDir(), t.TempDir()
+	windows, err := tmux.ParseWindows("home,logs=|")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "l" is a prefix of api's logs window but must be read as session l.
+	for name, d := range map[string]string{"api": dir, "l": other, "web": dir} {
+		if _, err := tmux.CreateSession(name, d, tmux.Layout{Windows: windows}); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+	}
+	snap, err := Take(true)
+	if err != nil {
+		t.Fatalf("take: %v", err)
+	}
+	if len(snap.Sessions) != 3 || len(snap.Sessions[0].Windows) != 2 || len(snap.Sessions[0].Windows[1].Panes) != 2 {
+		t.Fatalf("snapshot: %+v", snap)
+	}
+	if got, _ := filepath.EvalSymlinks(snap.Sessions[1].Dir); got != mustEvalSymlinks(t, other) {
+		t.Fatalf("session l saved in %q, want %q", snap.Sessions[1].Dir, other)
+	}
+	snap.Sessions = slices.Delete(snap.Sessions, 1, 2)
+	if snap.Sessions[0].Windows[0].Panes[0].Scrollback == "" {
+		t.Fatalf("expected scrollback to be captured")
+	}
+
+	if err := exec.Command("tmux", "-S", socket, "kill-session", "-t", "web").Run(); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+	results, err := Restore(snap)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	want := []Result{{Session: "api", Skipped: true}, {Session: "web"}}
+	if !reflect.DeepEqual(results, want) {
+		t.Fatalf("results = %+v, want %+v", results, want)
+	}
+	restored, err := tmux.ListWindows("web")
+	if err != nil || len(restored) != 2 || restored[1].Panes != 2 {
+		t.Fatalf("restored windows: %+v, %v", restored, err)
+	}
+
+	results, _ = Restore(snap)
+	for _, r := range results {
+		if !r.Skipped {
+			t.Fatalf("second restore should skip every session: %+v", results)
+		}
+	}
+}
+
+func mustEvalSymlinks(t *testing.T, path string) string {
+	t.Helper()
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolved
+}
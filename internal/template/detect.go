@@ -0,0 +1,102 @@
+package template
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+// Detect builds a template from the project files in dir: go.mod gives
+// editor, test and run windows, a package.json dev script a dev server,
+// a Makefile a build window and a compose file a logs window. It returns
+// the template and the files it was built from, or nil when dir has none
+// of them.
+func Detect(dir string) (*Template, []string) {
+	var windows []tmux.WindowSpec
+	var found []string
+	window := func(name, command string) {
+		windows = append(windows, tmux.WindowSpec{Name: name, Panes: []tmux.PaneSpec{{Command: command}}})
+	}
+
+	if exists(dir, "go.mod") {
+		found = append(found, "go.mod")
+		window("editor", editor()+" .")
+		window("test", "go test ./...")
+		window("run", "")
+	}
+	if hasDevScript(filepath.Join(dir, "package.json")) {
+		found = append(found, "package.json")
+		window("dev", packageManager(dir)+" dev")
+	}
+	if exists(dir, "Makefile") {
+		found = append(found, "Makefile")
+		window("build", "")
+	}
+	for _, name := range composeFiles {
+		if exists(dir, name) {
+			found = append(found, name)
+			window("logs", "docker compose logs -f")
+			break
+		}
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
+	// Without an editor window, the session still opens on a plain shell.
+	if windows[0].Name != "editor" {
+		windows = append([]tmux.WindowSpec{{Name: "home", Panes: []tmux.PaneSpec{{}}}}, windows...)
+	}
+	return &Template{Windows: windows}, found
+}
+
+// editor returns $EDITOR, or vi. It is resolved here rather than in the pane
+// so the command works in any shell, not just POSIX ones.
+func editor() string {
+	if e := strings.TrimSpace(os.Getenv("EDITOR")); e != "" {
+		return e
+	}
+	return "vi"
+}
+
+// composeFiles are the names docker compose looks for, in its order.
+var composeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// lockFiles pick the command running a package.json script.
+var lockFiles = []struct{ file, command string }{
+	{"pnpm-lock.yaml", "pnpm"},
+	{"yarn.lock", "yarn"},
+	{"bun.lock", "bun run"},
+	{"bun.lockb", "bun run"},
+}
+
+func packageManager(dir string) string {
+	for _, l := range lockFiles {
+		if exists(dir, l.file) {
+			return l.command
+		}
+	}
+	return "npm run"
+}
+
+func hasDevScript(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return false
+	}
+	_, ok := pkg.Scripts["dev"]
+	return ok
+}
+
+func exists(dir, name string) bool {
+	info, err := os.Stat(filepath.Join(dir, name))
+	return err == nil && info.Mode().IsRegular()
+}
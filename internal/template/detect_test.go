@@ -0,0 +1,82 @@
+package template
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		windows []string
+		found   []string
+	}{
+		{
+			name:    "go",
+			files:   map[string]string{"go.mod": "module x\n", "Makefile": "all:\n"},
+			windows: []string{"editor", "test", "run", "build"},
+			found:   []string{"go.mod", "Makefile"},
+		},
+		{
+			name:    "node with compose",
+			files:   map[string]string{"package.json": `{"scripts":{"dev":"vite"}}`, "docker-compose.yml": ""},
+			windows: []string{"home", "dev", "logs"},
+			found:   []string{"package.json", "docker-compose.yml"},
+		},
+		{
+			name:  "package without dev script",
+			files: map[string]string{"package.json": `{"scripts":{"test":"jest"}}`},
+		},
+		{
+			name: "nothing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				write(t, filepath.Join(dir, name), content)
+			}
+			tpl, found := Detect(dir)
+			if !reflect.DeepEqual(found, tt.found) {
+				t.Fatalf("found %q, want %q", found, tt.found)
+			}
+			var names []string
+			if tpl != nil {
+				for _, w := range tpl.Windows {
+					names = append(names, w.Name)
+				}
+			}
+			if !reflect.DeepEqual(names, tt.windows) {
+				t.Fatalf("windows %q, want %q", names, tt.windows)
+			}
+		})
+	}
+}
+
+func TestDetectEditor(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "go.mod"), "module x\n")
+	for editor, want := range map[string]string{"nvim": "nvim .", "code -w": "code -w .", "": "vi ."} {
+		t.Setenv("EDITOR", editor)
+		tpl, _ := Detect(dir)
+		if got := tpl.Windows[0].Panes[0].Command; got != want {
+			t.Errorf("EDITOR=%q: command %q, want %q", editor, got, want)
+		}
+	}
+}
+
+func TestDetectDevScriptCommand(t *testing.T) {
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "package.json"), `{"scripts":{"dev":"vite"}}`)
+	write(t, filepath.Join(dir, "pnpm-lock.yaml"), "")
+	tpl, _ := Detect(dir)
+	want := tmux.WindowSpec{Name: "dev", Panes: []tmux.PaneSpec{{Command: "pnpm dev"}}}
+	if !reflect.DeepEqual(tpl.Windows[1], want) {
+		t.Fatalf("dev window = %+v, want %+v", tpl.Windows[1], want)
+	}
+}
@@ -0,0 +1,293 @@
+// Package template reads project-local session templates: a .p file in a
+// project that declares the session p creates for it.
+package template
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+// FileName is the template's file name.
+const FileName = ".p"
+
+// Template is a parsed .p file.
+type Template struct {
+	// Name is the session name; empty uses the directory's name.
+	Name string
+	// Focus is the window selected once the session is built.
+	Focus string
+	// Env is set in the session's environment.
+	Env map[string]string
+	// Windows replace P_WINDOWS when there is at least one.
+	Windows []tmux.WindowSpec
+}
+
+// Layout returns the template's layout, using fallback's windows when the
+// template declares none.
+func (t *Template) Layout(fallback tmux.Layout) tmux.Layout {
+	layout := tmux.Layout{Windows: t.Windows, Focus: t.Focus, Env: t.Env}
+	if len(layout.Windows) == 0 {
+		layout.Windows = fallback.Windows
+	}
+	return layout
+}
+
+// Find returns the path of the template for dir: dir's own .p file, or
+// else the one at the root of the git repository containing dir. It returns
+// "" when there is none.
+func Find(dir string) (string, error) {
+	candidates := []string{filepath.Join(dir, FileName)}
+	if root := gitRoot(dir); root != "" && root != dir {
+		candidates = append(candidates, filepath.Join(root, FileName))
+	}
+	for _, path := range candidates {
+		info, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+// Load finds and parses the template for dir. It returns nil when there is
+// none. Relative directories in the template are resolved against the
+// directory holding it.
+func Load(dir string) (*Template, error) {
+	path, err := Find(dir)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	base := filepath.Dir(path)
+	for i := range t.Windows {
+		for j := range t.Windows[i].Panes {
+			pane := &t.Windows[i].Panes[j]
+			if pane.Dir != "" && !filepath.IsAbs(pane.Dir) {
+				pane.Dir = filepath.Join(base, pane.Dir)
+			}
+		}
+	}
+	return t, nil
+}
+
+// gitRoot returns the nearest directory at or above dir containing .git,
+// or "" when dir is not in a repository.
+func gitRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Parse reads a template. The format is INI-like:
+//
+//	name = api
+//	focus = server
+//
+//	[env]
+//	PORT = 8080
+//
+//	[window editor]
+//	command = nvim .
+//
+//	[window server]
+//	layout = even-horizontal
+//	dir = services/api
+//	[pane]
+//	command = npm run dev
+//	[pane]
+//	command = tail -f log/dev.log
+//
+// Top-level keys come before the first section. A window's command is
+// shorthand for a single pane; [pane] sections add panes to the window
+// above them, and its dir is the default for those panes. Lines starting
+// with # are comments.
+func Parse(r io.Reader) (*Template, error) {
+	p := parser{t: &Template{}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.line++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var err error
+		if strings.HasPrefix(line, "[") {
+			err = p.section(line)
+		} else {
+			err = p.keyValue(line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.finish(); err != nil {
+		return nil, err
+	}
+	return p.t, nil
+}
+
+// Section kinds.
+const (
+	sectionTop = iota
+	sectionEnv
+	sectionWindow
+	sectionPane
+)
+
+type parser struct {
+	t    *Template
+	line int
+	kind int
+	// window is the window being read, with its dir and command until
+	// finishWindow turns them into panes.
+	window  *tmux.WindowSpec
+	dir     string
+	command string
+}
+
+func (p *parser) section(line string) error {
+	if !strings.HasSuffix(line, "]") {
+		return fmt.Errorf("unterminated section %s", line)
+	}
+	kind, name, _ := strings.Cut(strings.TrimSpace(line[1:len(line)-1]), " ")
+	name = strings.TrimSpace(name)
+	switch kind {
+	case "env":
+		p.finishWindow()
+		p.kind = sectionEnv
+	case "window":
+		p.finishWindow()
+		if name == "" {
+			return errors.New("[window] needs a name")
+		}
+		p.window = &tmux.WindowSpec{Name: name}
+		p.kind = sectionWindow
+	case "pane":
+		if p.window == nil {
+			return errors.New("[pane] must follow a [window]")
+		}
+		if p.command != "" {
+			return fmt.Errorf("window %q sets command and has [pane] sections", p.window.Name)
+		}
+		p.window.Panes = append(p.window.Panes, tmux.PaneSpec{})
+		p.kind = sectionPane
+	default:
+		return fmt.Errorf("unknown section %s", line)
+	}
+	return nil
+}
+
+func (p *parser) keyValue(line string) error {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return fmt.Errorf("expected key = value, got %q", line)
+	}
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if key == "" {
+		return fmt.Errorf("missing key in %q", line)
+	}
+	switch p.kind {
+	case sectionTop:
+		switch key {
+		case "name":
+			p.t.Name = value
+		case "focus":
+			p.t.Focus = value
+		default:
+			return fmt.Errorf("unknown key %q", key)
+		}
+	case sectionEnv:
+		if p.t.Env == nil {
+			p.t.Env = map[string]string{}
+		}
+		p.t.Env[key] = value
+	case sectionWindow:
+		switch key {
+		case "layout":
+			if err := tmux.CheckArrangement(value); err != nil {
+				return fmt.Errorf("window %q: %w", p.window.Name, err)
+			}
+			p.window.Arrangement = value
+		case "dir":
+			p.dir = value
+		case "command":
+			p.command = value
+		default:
+			return fmt.Errorf("unknown window key %q", key)
+		}
+	case sectionPane:
+		pane := &p.window.Panes[len(p.window.Panes)-1]
+		switch key {
+		case "dir":
+			pane.Dir = value
+		case "command":
+			pane.Command = value
+		default:
+			return fmt.Errorf("unknown pane key %q", key)
+		}
+	}
+	return nil
+}
+
+// finishWindow appends the window being read, giving it its single pane or
+// its default directory.
+func (p *parser) finishWindow() {
+	w := p.window
+	if w == nil {
+		return
+	}
+	if len(w.Panes) == 0 {
+		w.Panes = []tmux.PaneSpec{{Command: p.command}}
+	}
+	for i := range w.Panes {
+		if w.Panes[i].Dir == "" {
+			w.Panes[i].Dir = p.dir
+		}
+	}
+	p.t.Windows = append(p.t.Windows, *w)
+	p.window, p.dir, p.command = nil, "", ""
+}
+
+func (p *parser) finish() error {
+	p.finishWindow()
+	if p.t.Focus == "" || len(p.t.Windows) == 0 {
+		return nil
+	}
+	for _, w := range p.t.Windows {
+		if w.Name == p.t.Focus {
+			return nil
+		}
+	}
+	return fmt.Errorf("focus %q is not a window", p.t.Focus)
+}
@@ -0,0 +1,134 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+const sample = `# workspace for the api
+name = api
+focus = server
+
+[env]
+PORT = 8080
+
+[window editor]
+command = nvim .
+
+[window server]
+layout = even-horizontal
+dir = services/api
+[pane]
+command = npm run dev
+[pane]
+dir = web
+command = npm run watch
+
+[window shell]
+`
+
+func TestParse(t *testing.T) {
+	got, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &Template{
+		Name:  "api",
+		Focus: "server",
+		Env:   map[string]string{"PORT": "8080"},
+		Windows: []tmux.WindowSpec{
+			{Name: "editor", Panes: []tmux.PaneSpec{{Command: "nvim ."}}},
+			{Name: "server", Arrangement: "even-horizontal", Panes: []tmux.PaneSpec{
+				{Command: "npm run dev", Dir: "services/api"},
+				{Command: "npm run watch", Dir: "web"},
+			}},
+			{Name: "shell", Panes: []tmux.PaneSpec{{}}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("template mismatch:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown key":        "colour = red",
+		"unknown section":    "[tabs]",
+		"unnamed window":     "[window]",
+		"orphan pane":        "[pane]",
+		"missing equals":     "[window a]\ncommand",
+		"bad layout":         "[window a]\nlayout = diagonal",
+		"command and panes":  "[window a]\ncommand = x\n[pane]",
+		"unknown pane key":   "[window a]\n[pane]\nlayout = tiled",
+		"focus not a window": "focus = b\n[window a]",
+	}
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(input)); err == nil {
+				t.Fatalf("expected error for %q", input)
+			}
+		})
+	}
+}
+
+func TestParseErrorHasLine(t *testing.T) {
+	_, err := Parse(strings.NewReader("name = a\n\n[window a]\nsize = 3\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 4:") {
+		t.Fatalf("expected line 4 error, got %v", err)
+	}
+}
+
+func TestLayoutFallsBackToWindows(t *testing.T) {
+	fallback := tmux.Layout{Windows: []tmux.WindowSpec{{Name: "home", Panes: []tmux.PaneSpec{{}}}}}
+	tpl := &Template{Env: map[string]string{"A": "1"}}
+	got := tpl.Layout(fallback)
+	if !reflect.DeepEqual(got.Windows, fallback.Windows) || got.Env["A"] != "1" {
+		t.Fatalf("layout mismatch: %+v", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "services", "api")
+	if err := os.MkdirAll(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if tpl, err := Load(sub); err != nil || tpl != nil {
+		t.Fatalf("expected no template, got %+v, %v", tpl, err)
+	}
+
+	write(t, filepath.Join(root, FileName), "[window api]\ndir = services/api\n")
+	tpl, err := Load(sub)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := tpl.Windows[0].Panes[0].Dir; got != sub {
+		t.Fatalf("git root template dir = %q, want %q", got, sub)
+	}
+
+	write(t, filepath.Join(sub, FileName), "name = local\n")
+	if tpl, err = Load(sub); err != nil || tpl.Name != "local" {
+		t.Fatalf("expected the directory's own template, got %+v, %v", tpl, err)
+	}
+
+	write(t, filepath.Join(sub, FileName), "[window]\n")
+	if _, err := Load(sub); err == nil || !strings.Contains(err.Error(), filepath.Join(sub, FileName)) {
+		t.Fatalf("expected error naming the file, got %v", err)
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
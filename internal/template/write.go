@@ -0,0 +1,133 @@
+package template
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+// FromLayout builds a template from a layout, such as one captured from a
+// running session. Pane directories are made relative to base, the
+// directory the template will be saved in, and dropped when they are base.
+func FromLayout(name string, layout tmux.Layout, base string) *Template {
+	t := &Template{Name: name, Focus: layout.Focus, Env: layout.Env}
+	for _, w := range layout.Windows {
+		spec := tmux.WindowSpec{Name: w.Name, Arrangement: w.Arrangement}
+		for _, p := range w.Panes {
+			p.Dir = relativeDir(base, p.Dir)
+			spec.Panes = append(spec.Panes, p)
+		}
+		t.Windows = append(t.Windows, spec)
+	}
+	return t
+}
+
+func relativeDir(base, dir string) string {
+	if dir == "" || !filepath.IsAbs(dir) {
+		return dir
+	}
+	rel, err := filepath.Rel(base, dir)
+	switch {
+	case err != nil || rel == ".." || strings.HasPrefix(rel, "../"):
+		return dir
+	case rel == ".":
+		return ""
+	default:
+		return rel
+	}
+}
+
+// Write writes t in the format Parse reads. A window with one pane is
+// written in the shorthand form.
+func Write(w io.Writer, t *Template) error {
+	if err := check(t); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	line := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(bw, "%s = %s\n", key, value)
+		}
+	}
+	line("name", t.Name)
+	line("focus", t.Focus)
+	if len(t.Env) > 0 {
+		fmt.Fprint(bw, "\n[env]\n")
+		for _, key := range slices.Sorted(maps.Keys(t.Env)) {
+			fmt.Fprintf(bw, "%s = %s\n", key, t.Env[key])
+		}
+	}
+	for _, win := range t.Windows {
+		fmt.Fprintf(bw, "\n[window %s]\n", win.Name)
+		line("layout", win.Arrangement)
+		if len(win.Panes) == 1 {
+			line("dir", win.Panes[0].Dir)
+			line("command", win.Panes[0].Command)
+			continue
+		}
+		for _, p := range win.Panes {
+			fmt.Fprint(bw, "[pane]\n")
+			line("dir", p.Dir)
+			line("command", p.Command)
+		}
+	}
+	return bw.Flush()
+}
+
+// check reports values the format cannot hold.
+func check(t *Template) error {
+	values := []string{t.Name, t.Focus}
+	for key, value := range t.Env {
+		if key == "" || strings.ContainsAny(key, "=\n") {
+			return fmt.Errorf("invalid environment variable name %q", key)
+		}
+		values = append(values, value)
+	}
+	for _, w := range t.Windows {
+		if strings.TrimSpace(w.Name) == "" {
+			return errors.New("window has no name")
+		}
+		values = append(values, w.Name, w.Arrangement)
+		for _, p := range w.Panes {
+			values = append(values, p.Dir, p.Command)
+		}
+	}
+	for _, v := range values {
+		if strings.Contains(v, "\n") {
+			return fmt.Errorf("value %q spans several lines", v)
+		}
+	}
+	return nil
+}
+
+// Save writes t to path. It refuses to replace an existing file unless
+// overwrite is set.
+func Save(path string, t *Template, overwrite bool) error {
+	if err := check(t); err != nil {
+		return err
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists (use --force to replace it)", path)
+	}
+	if err != nil {
+		return err
+	}
+	if err := Write(f, t); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,84 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+func TestWriteRoundTrip(t *testing.T) {
+	want, err := Parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var out strings.Builder
+	if err := Write(&out, want); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	got, err := Parse(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatalf("reparse: %v\n%s", err, out.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v\n%s", got, want, out.String())
+	}
+}
+
+func TestWriteRejectsMultilineValues(t *testing.T) {
+	tpl := &Template{Windows: []tmux.WindowSpec{{Name: "a", Panes: []tmux.PaneSpec{{Command: "echo a\necho b"}}}}}
+	if err := Write(&strings.Builder{}, tpl); err == nil {
+		t.Fatalf("expected an error for a multi-line command")
+	}
+}
+
+func TestFromLayout(t *testing.T) {
+	layout := tmux.Layout{
+		Focus: "web",
+		Windows: []tmux.WindowSpec{
+			{Name: "home", Panes: []tmux.PaneSpec{{Dir: "/work/api"}}},
+			{Name: "web", Arrangement: "tiled", Panes: []tmux.PaneSpec{
+				{Dir: "/work/api/web", Command: "node"},
+				{Dir: "/srv/logs"},
+				{Dir: "/work/apiary"},
+			}},
+		},
+	}
+	got := FromLayout("api", layout, "/work/api")
+	want := &Template{
+		Name:  "api",
+		Focus: "web",
+		Windows: []tmux.WindowSpec{
+			{Name: "home", Panes: []tmux.PaneSpec{{}}},
+			{Name: "web", Arrangement: "tiled", Panes: []tmux.PaneSpec{
+				{Dir: "web", Command: "node"},
+				{Dir: "/srv/logs"},
+				{Dir: "/work/apiary"},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("template mismatch:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestSaveKeepsExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	write(t, path, "name = mine\n")
+	tpl := &Template{Name: "new"}
+	if err := Save(path, tpl, false); err == nil {
+		t.Fatalf("expected an error for an existing file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "name = mine\n" {
+		t.Fatalf("existing file changed: %q", data)
+	}
+	if err := Save(path, tpl, true); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "name = new\n" {
+		t.Fatalf("file not replaced: %q", data)
+	}
+}
@@ -0,0 +1,74 @@
+package tmux
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// accentOption is the session user option that pins a session's accent
+// colour, e.g. `tmux set -t api @p_accent colour33`. It wins over P_ACCENT.
+const accentOption = "@p_accent"
+
+// accentColourOption records the accent p applied, so the selector can show
+// the same colour.
+const accentColourOption = "@p_accent_colour"
+
+// accentPalette holds 256-colour accents that stay readable on the default
+// dark status bar and distinct from one another.
+var accentPalette = []string{
+	"colour108", "colour110", "colour139", "colour173", "colour179", "colour167",
+	"colour73", "colour143", "colour175", "colour109", "colour180", "colour66",
+}
+
+// Accent modes for P_ACCENT. Any other value is a colour used for every
+// session.
+const (
+	accentByName = "name"
+	accentByPath = "path"
+)
+
+var accentColourPattern = regexp.MustCompile(`^(colou?r\d{1,3}|#[0-9a-fA-F]{6}|(bright)?(black|red|green|yellow|blue|magenta|cyan|white))$`)
+
+// CheckAccent reports an invalid P_ACCENT.
+func CheckAccent() error {
+	mode := strings.TrimSpace(os.Getenv("P_ACCENT"))
+	switch mode {
+	case "", accentByName, accentByPath:
+		return nil
+	}
+	if !accentColourPattern.MatchString(mode) {
+		return fmt.Errorf("P_ACCENT: %q is not name, path or a tmux colour", mode)
+	}
+	return nil
+}
+
+// sessionAccent picks a session's accent colour: the session's @p_accent
+// when pinned, otherwise as P_ACCENT says. Empty means the theme's accent.
+func sessionAccent(sessionName, sessionPath, pinned string) string {
+	if pinned != "" {
+		return pinned
+	}
+	mode := strings.TrimSpace(os.Getenv("P_ACCENT"))
+	switch mode {
+	case "":
+		return ""
+	case accentByName:
+		return hashedAccent(sessionName)
+	case accentByPath:
+		return hashedAccent(sessionPath)
+	}
+	if CheckAccent() != nil {
+		return ""
+	}
+	return mode
+}
+
+// hashedAccent maps a key to a stable palette colour.
+func hashedAccent(key string) string {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return accentPalette[h.Sum32()%uint32(len(accentPalette))]
+}
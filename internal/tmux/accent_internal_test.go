@@ -0,0 +1,69 @@
+package tmux
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSessionAccent(t *testing.T) {
+	for _, tc := range []struct {
+		mode, pinned, want string
+	}{
+		{mode: "", want: ""},
+		{mode: "", pinned: "red", want: "red"},
+		{mode: "name", want: hashedAccent("api")},
+		{mode: "path", want: hashedAccent("/work/api")},
+		{mode: "path", pinned: "colour33", want: "colour33"},
+		{mode: "#ff8800", want: "#ff8800"},
+		{mode: "not-a-colour", want: ""},
+	} {
+		t.Setenv("P_ACCENT", tc.mode)
+		if got := sessionAccent("api", "/work/api", tc.pinned); got != tc.want {
+			t.Fatalf("%+v: got %q", tc, got)
+		}
+	}
+}
+
+func TestHashedAccentIsStable(t *testing.T) {
+	if hashedAccent("api") != hashedAccent("api") {
+		t.Fatalf("accent changed between calls")
+	}
+	if !slices.Contains(accentPalette, hashedAccent("web")) {
+		t.Fatalf("accent outside the palette")
+	}
+}
+
+func TestCheckAccent(t *testing.T) {
+	for _, ok := range []string{"", "name", "path", "colour108", "color7", "#A0b1c2", "brightblue"} {
+		t.Setenv("P_ACCENT", ok)
+		if err := CheckAccent(); err != nil {
+			t.Fatalf("%q: unexpected error %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"hash", "#fff", "colourful"} {
+		t.Setenv("P_ACCENT", bad)
+		if err := CheckAccent(); err == nil {
+			t.Fatalf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestConfigureCommandsAppliesAccent(t *testing.T) {
+	b := layeredConfigureCommands("api", nil, "colour173", userSettings{})
+	var left, stored []string
+	for _, c := range b {
+		if len(c) == 4 && c[2] == "status-left" {
+			left = c
+		}
+		if len(c) == 4 && c[2] == accentColourOption {
+			stored = c
+		}
+	}
+	if want := []string{"set-option", "-tapi", "status-left", "#[fg=colour173][#S] "}; !reflect.DeepEqual(left, want) {
+		t.Fatalf("status-left: got %q want %q", left, want)
+	}
+	if stored == nil || stored[3] != "colour173" {
+		t.Fatalf("accent not recorded: %q", stored)
+	}
+}
@@ -0,0 +1,215 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ConfigFile returns the configuration file tmux servers are started with.
+// By default this is /dev/null (ADR-002). P_TMUX_CONFIG opts in to a real
+// configuration: "user" loads the user's own tmux.conf, any other value is
+// the path of a file, such as a p-specific config.
+func ConfigFile() (string, error) {
+	value := strings.TrimSpace(os.Getenv("P_TMUX_CONFIG"))
+	switch value {
+	case "", "none":
+		return os.DevNull, nil
+	case "user":
+		for _, path := range userConfigPaths() {
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+		return os.DevNull, nil
+	}
+	path := expandHome(value)
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("P_TMUX_CONFIG: %w", err)
+	}
+	return path, nil
+}
+
+// configFlags returns the -f flag for tmux invocations. An unreadable
+// P_TMUX_CONFIG falls back to /dev/null; callers check ConfigFile up front.
+func configFlags() []string {
+	path, err := ConfigFile()
+	if err != nil {
+		path = os.DevNull
+	}
+	return []string{"-f", path}
+}
+
+// userConfigPaths lists where tmux looks for its configuration, in order.
+func userConfigPaths() []string {
+	home, _ := os.UserHomeDir()
+	paths := []string{filepath.Join(home, ".tmux.conf")}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		paths = append(paths, filepath.Join(xdg, "tmux", "tmux.conf"))
+	}
+	return append(paths, filepath.Join(home, ".config", "tmux", "tmux.conf"))
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+// userSettings is what the user's tmux configuration changed: option names
+// and key bindings as "table key".
+type userSettings struct {
+	options  map[string]bool
+	bindings map[string]bool
+}
+
+// settingsCommand lists the global options and the copy-mode-vi bindings, the
+// settings p's configuration can clash with.
+var settingsCommand = []string{"show-options", "-g", ";", "show-options", "-gw", ";", "list-keys", "-T", "copy-mode-vi"}
+
+// loadUserSettings reads what the server's configuration changed, by
+// comparing the running server with a server started from no configuration.
+// Whatever set the values, be it the file itself, run-shell, if-shell or a
+// plugin, counts as the user's. It is empty in the default /dev/null mode.
+func loadUserSettings() userSettings {
+	path, err := ConfigFile()
+	if err != nil || path == os.DevNull {
+		return userSettings{}
+	}
+	current, err := runner.Run(settingsCommand...)
+	defaults := defaultSettings()
+	if err != nil || defaults == "" {
+		return userSettings{}
+	}
+	return changedSettings(current, defaults)
+}
+
+// defaultSettings is tmux's built-in settings, read once from a short-lived
+// server on a private socket.
+var defaultSettings = sync.OnceValue(func() string {
+	dir, err := os.MkdirTemp("", "p-defaults-")
+	if err != nil {
+		return ""
+	}
+	defer os.RemoveAll(dir)
+	args := append([]string{"-u", "-f", os.DevNull, "-S", filepath.Join(dir, "s"), "start-server", ";"}, settingsCommand...)
+	out, err := exec.Command("tmux", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return string(out)
+})
+
+// changedSettings compares settingsCommand output from the user's server with
+// the defaults. p's own copy-mode-vi bindings are server-wide, so they don't
+// count as changes.
+func changedSettings(current, defaults string) userSettings {
+	options, bindings := parseSettings(current)
+	defaultOptions, defaultBindings := parseSettings(defaults)
+	s := userSettings{options: map[string]bool{}, bindings: map[string]bool{}}
+	for name, value := range options {
+		if d, ok := defaultOptions[name]; !ok || d != value {
+			s.options[name] = true
+		}
+	}
+	injected := map[string]string{}
+	for _, b := range copyModeBindings() {
+		injected[b[2]+" "+b[3]] = strings.Join(b[4:], " ")
+	}
+	for key, command := range bindings {
+		if command != defaultBindings[key] && command != injected[key] {
+			s.bindings[key] = true
+		}
+	}
+	for key := range defaultBindings {
+		if _, ok := bindings[key]; !ok {
+			s.bindings[key] = true
+		}
+	}
+	return s
+}
+
+// parseSettings splits settingsCommand output into option values and bound
+// commands keyed by "table key".
+func parseSettings(out string) (options, bindings map[string]string) {
+	options, bindings = map[string]string{}, map[string]string{}
+	for _, line := range splitLines(strings.TrimSpace(out)) {
+		if fields := strings.Fields(line); len(fields) >= 4 && fields[0] == "bind-key" && fields[1] == "-T" {
+			bindings[fields[2]+" "+fields[3]] = strings.Join(fields[4:], " ")
+			continue
+		}
+		name, value, _ := strings.Cut(line, " ")
+		options[name] = value
+	}
+	return options, bindings
+}
+
+// parseFlags splits command arguments getopt-style into flags and operands.
+// Flags listed in withValue take the following argument.
+func parseFlags(args []string, withValue string) (map[rune]string, []string) {
+	flags := map[rune]string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return flags, args[i+1:]
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return flags, args[i:]
+		}
+		for j, f := range arg[1:] {
+			if !strings.ContainsRune(withValue, f) {
+				flags[f] = ""
+				continue
+			}
+			if rest := arg[2+j:]; rest != "" {
+				flags[f] = rest
+			} else if i+1 < len(args) {
+				i++
+				flags[f] = args[i]
+			}
+			break
+		}
+	}
+	return flags, nil
+}
+
+// setsOption reports whether the user configuration sets an option p
+// injects. Setting an array option as a whole covers all of its indexes.
+func (s userSettings) setsOption(name string) bool {
+	return s.options[name] || s.options[optionBase(name)]
+}
+
+// layerOptions drops the injected options the user configuration sets, so
+// p's configuration is a base layer beneath the user's.
+func layerOptions(injected []option, user userSettings) []option {
+	var layered []option
+	for _, o := range injected {
+		if !user.setsOption(o.name) {
+			layered = append(layered, o)
+		}
+	}
+	return layered
+}
+
+// layerBindings drops injected bind-key commands for keys the user
+// configuration binds in the same table.
+func layerBindings(injected [][]string, user userSettings) [][]string {
+	var layered [][]string
+	for _, b := range injected {
+		flags, operands := parseFlags(b[1:], "TN")
+		table := "prefix"
+		if t, ok := flags['T']; ok {
+			table = t
+		}
+		if len(operands) == 0 || !user.bindings[table+" "+operands[0]] {
+			layered = append(layered, b)
+		}
+	}
+	return layered
+}
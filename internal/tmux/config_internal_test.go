@@ -0,0 +1,95 @@
+package tmux
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestChangedSettings(t *testing.T) {
+	defaults := `status-style bg=green,fg=black
+status-left "[#{session_name}] "
+status-format[0] "#[align=left]"
+mode-keys emacs
+bind-key    -T copy-mode-vi v                    send-keys -X rectangle-toggle
+bind-key    -T copy-mode-vi y                    send-keys -X copy-selection-and-cancel
+bind-key    -T copy-mode-vi q                    send-keys -X cancel
+bind-key    -T copy-mode-vi Escape               send-keys -X clear-selection
+`
+	current := `status-style bg=green,fg=black
+status-left "#S "
+status-format[0] "#[align=centre]"
+mode-keys emacs
+@plugin tmux-plugins/tmux-sensible
+bind-key    -T copy-mode-vi v                    send-keys -X begin-selection
+bind-key    -T copy-mode-vi y                    send-keys -X copy-pipe
+bind-key    -T copy-mode-vi q                    send-keys -X cancel
+`
+	s := changedSettings(current, defaults)
+	wantOptions := map[string]bool{"status-left": true, "status-format[0]": true, "@plugin": true}
+	if !reflect.DeepEqual(s.options, wantOptions) {
+		t.Fatalf("options: got %v want %v", s.options, wantOptions)
+	}
+	// p's own v binding is not the user's; Escape was unbound.
+	wantBindings := map[string]bool{"copy-mode-vi y": true, "copy-mode-vi Escape": true}
+	if !reflect.DeepEqual(s.bindings, wantBindings) {
+		t.Fatalf("bindings: got %v want %v", s.bindings, wantBindings)
+	}
+}
+
+func TestLayeredConfigureCommandsDefersToUser(t *testing.T) {
+	user := userSettings{
+		options:  map[string]bool{"status-style": true, "window-style": true},
+		bindings: map[string]bool{"copy-mode-vi y": true},
+	}
+	b := layeredConfigureCommands("api", []string{"api:0"}, "", user)
+
+	for _, c := range b {
+		for _, arg := range c {
+			if arg == "status-style" || arg == "window-style" {
+				t.Fatalf("user option overridden: %q", c)
+			}
+		}
+		if c[0] == "bind-key" && c[3] == "y" {
+			t.Fatalf("user binding overridden: %q", c)
+		}
+	}
+	if !reflect.DeepEqual(b[0], copyModeBindings()[0]) {
+		t.Fatalf("expected the v binding to remain, got %q", b[0])
+	}
+
+	if got, want := len(layeredConfigureCommands("api", []string{"api:0"}, "", userSettings{})), len(b)+3; got != want {
+		t.Fatalf("without a user config: got %d commands want %d", got, want)
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	for _, value := range []string{"", "none", "user"} {
+		t.Setenv("P_TMUX_CONFIG", value)
+		if got, err := ConfigFile(); err != nil || got != os.DevNull {
+			t.Fatalf("%q: got %q, %v", value, got, err)
+		}
+	}
+
+	conf := filepath.Join(home, ".tmux.conf")
+	if err := os.WriteFile(conf, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("P_TMUX_CONFIG", "user")
+	if got, _ := ConfigFile(); got != conf {
+		t.Fatalf("user: got %q want %q", got, conf)
+	}
+	t.Setenv("P_TMUX_CONFIG", "~/.tmux.conf")
+	if got, _ := ConfigFile(); got != conf {
+		t.Fatalf("path: got %q want %q", got, conf)
+	}
+	t.Setenv("P_TMUX_CONFIG", "~/missing.conf")
+	if _, err := ConfigFile(); err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
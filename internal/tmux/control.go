@@ -0,0 +1,295 @@
+package tmux
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Notification is an asynchronous control-mode message such as
+// %sessions-changed or %window-add, split into its name and arguments.
+type Notification struct {
+	Name string
+	Args []string
+}
+
+// ControlClient is a long-lived tmux control-mode connection (tmux -C).
+// Commands are written to the client's stdin and their %begin/%end replies
+// parsed from stdout, so many commands share a single tmux process.
+//
+// ControlClient implements Runner; install it with SetRunner to route the
+// package's listing and configuration calls over the connection. Exec still
+// replaces the process with the tmux binary.
+type ControlClient struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+
+	mu      sync.Mutex
+	waiters []chan controlReply
+	session string
+	closed  bool
+
+	notifications chan Notification
+	ready         chan error
+	done          chan struct{}
+}
+
+type controlReply struct {
+	output []string
+	failed bool
+	err    error
+}
+
+// errControlClosed is returned for commands issued after the connection ended.
+var errControlClosed = errors.New("tmux control connection closed")
+
+// DialControl starts a control-mode client on the selected server. The
+// client attaches to the most recently used session without receiving pane
+// output or affecting window sizes, so the server must have a session.
+func DialControl() (*ControlClient, error) {
+	// attach-session would start a server only for it to exit again, racing
+	// whatever starts the real one next.
+	if _, err := (execRunner{}).Run("has-session"); err != nil {
+		return nil, err
+	}
+	args := append([]string{"-u"}, tmuxArgs("-C", "attach-session", "-f", "no-output,ignore-size,read-only")...)
+	c := &ControlClient{
+		cmd:           exec.Command("tmux", args...),
+		notifications: make(chan Notification, 64),
+		ready:         make(chan error, 1),
+		done:          make(chan struct{}),
+	}
+	c.cmd.Stderr = &c.stderr
+	stdin, err := c.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := c.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	c.stdin = stdin
+	if err := c.cmd.Start(); err != nil {
+		return nil, err
+	}
+	go c.read(stdout)
+
+	if err := <-c.ready; err != nil {
+		c.Close()
+		if msg := strings.TrimSpace(c.stderr.String()); msg != "" {
+			return nil, &CommandError{Args: []string{"-C", "attach-session"}, Stderr: msg, Err: err}
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Command runs one tmux command over the connection and returns its output.
+func (c *ControlClient) Command(args ...string) (string, error) {
+	reply := make(chan controlReply, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return "", errControlClosed
+	}
+	line := batchScript([][]string{args})
+	if _, err := io.WriteString(c.stdin, line); err != nil {
+		c.mu.Unlock()
+		return "", err
+	}
+	c.waiters = append(c.waiters, reply)
+	c.mu.Unlock()
+
+	r := <-reply
+	if r.err != nil {
+		return "", r.err
+	}
+	out := strings.Join(r.output, "\n")
+	if r.failed {
+		return "", &CommandError{Args: args, Stderr: strings.TrimSpace(out), Err: errors.New("tmux command failed")}
+	}
+	if out != "" {
+		out += "\n"
+	}
+	return out, nil
+}
+
+// Notifications delivers asynchronous messages from the server. The channel
+// is closed when the connection ends. Notifications are dropped rather than
+// blocking the connection when nobody is reading.
+func (c *ControlClient) Notifications() <-chan Notification {
+	return c.notifications
+}
+
+// Close ends the control-mode client. tmux exits when its stdin closes.
+func (c *ControlClient) Close() error {
+	c.stdin.Close()
+	<-c.done
+	return c.cmd.Wait()
+}
+
+// Run implements Runner.
+func (c *ControlClient) Run(args ...string) (string, error) {
+	return c.Command(args...)
+}
+
+// Batch implements Runner. Every command runs; failures are joined.
+func (c *ControlClient) Batch(commands [][]string) error {
+	var failures []string
+	var last error
+	for _, args := range commands {
+		if _, err := c.Command(args...); err != nil {
+			failures = append(failures, err.Error())
+			last = err
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return &CommandError{Args: []string{"batch"}, Stderr: strings.Join(failures, "\n"), Err: last}
+}
+
+// Exec implements Runner by handing the terminal to the tmux binary.
+func (c *ControlClient) Exec(args ...string) error {
+	return execRunner{}.Exec(args...)
+}
+
+// attachedSession is the session the control client itself is attached to.
+// ListSessions discounts it so the connection doesn't mark it attached.
+func (c *ControlClient) attachedSession() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.session
+}
+
+func (c *ControlClient) read(stdout io.Reader) {
+	defer close(c.done)
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	attached := false
+	var block []string
+	inBlock := false
+	blockID := ""
+	fromClient := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inBlock {
+			if failed, ok := parseBlockEnd(line, blockID); ok {
+				inBlock = false
+				if !attached {
+					// The first block answers the attach-session itself.
+					attached = true
+					c.signalReady(block, failed)
+					continue
+				}
+				if fromClient {
+					c.deliver(controlReply{output: block, failed: failed})
+				}
+				continue
+			}
+			block = append(block, line)
+			continue
+		}
+		if strings.HasPrefix(line, "%begin ") {
+			fields := strings.Fields(line)
+			inBlock = true
+			block = nil
+			blockID = blockNumbers(fields)
+			fromClient = len(fields) >= 4 && fields[3] == "1"
+			continue
+		}
+		if strings.HasPrefix(line, "%") {
+			c.notify(line)
+		}
+	}
+	c.shutdown()
+}
+
+// blockNumbers returns the time and command number of a %begin, %end or
+// %error line, which tmux repeats on the lines opening and closing a reply.
+func blockNumbers(fields []string) string {
+	if len(fields) < 3 {
+		return ""
+	}
+	return fields[1] + " " + fields[2]
+}
+
+// parseBlockEnd recognizes the %end and %error lines closing the reply
+// begun with id. Output lines that merely look like them, such as captured
+// pane content, carry other numbers or none.
+func parseBlockEnd(line, id string) (failed bool, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || blockNumbers(fields) != id {
+		return false, false
+	}
+	switch fields[0] {
+	case "%end":
+		return false, true
+	case "%error":
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+func (c *ControlClient) signalReady(output []string, failed bool) {
+	if failed {
+		c.ready <- fmt.Errorf("tmux control mode: %s", strings.Join(output, "\n"))
+		return
+	}
+	c.ready <- nil
+}
+
+func (c *ControlClient) deliver(r controlReply) {
+	c.mu.Lock()
+	if len(c.waiters) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	w := c.waiters[0]
+	c.waiters = c.waiters[1:]
+	c.mu.Unlock()
+	w <- r
+}
+
+func (c *ControlClient) notify(line string) {
+	fields := strings.Fields(strings.TrimPrefix(line, "%"))
+	if len(fields) == 0 {
+		return
+	}
+	n := Notification{Name: fields[0], Args: fields[1:]}
+	if n.Name == "session-changed" && len(n.Args) >= 2 {
+		c.mu.Lock()
+		c.session = strings.Join(n.Args[1:], " ")
+		c.mu.Unlock()
+	}
+	select {
+	case c.notifications <- n:
+	default:
+	}
+}
+
+// shutdown fails pending commands once the client exits.
+func (c *ControlClient) shutdown() {
+	c.mu.Lock()
+	c.closed = true
+	waiters := c.waiters
+	c.waiters = nil
+	c.mu.Unlock()
+
+	for _, w := range waiters {
+		w <- controlReply{err: errControlClosed}
+	}
+	select {
+	case c.ready <- errControlClosed:
+	default:
+	}
+	close(c.notifications)
+}
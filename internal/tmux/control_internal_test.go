@@ -0,0 +1,117 @@
+package tmux
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// pipeControl wires a ControlClient to an in-process fake server that
+// answers each command line with reply.
+func pipeControl(t *testing.T, reply func(line string) (output string, failed bool)) *ControlClient {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &ControlClient{
+		stdin:         inW,
+		notifications: make(chan Notification, 64),
+		ready:         make(chan error, 1),
+		done:          make(chan struct{}),
+	}
+	go c.read(outR)
+	go func() {
+		defer outW.Close()
+		io.WriteString(outW, "%begin 1 1 0\n%end 1 1 0\n%session-changed $0 main\n")
+		in := bufio.NewScanner(inR)
+		for n := 2; in.Scan(); n++ {
+			// Unrelated output between replies must be ignored.
+			io.WriteString(outW, "%window-add @3\n%begin 5 99 0\nstray\n%end 5 99 0\n")
+			output, failed := reply(in.Text())
+			end := "%end"
+			if failed {
+				end = "%error"
+			}
+			fmt.Fprintf(outW, "%%begin 1 %d 1\n%s%s 1 %d 1\n", n, output, end, n)
+		}
+	}()
+	t.Cleanup(func() { inW.Close() })
+	if err := <-c.ready; err != nil {
+		t.Fatalf("handshake: %v", err)
+	}
+	return c
+}
+
+func TestControlClientCommand(t *testing.T) {
+	c := pipeControl(t, func(line string) (string, bool) {
+		if line == `'has-session' '-t' 'gone'` {
+			return "can't find session: gone\n", true
+		}
+		return "api\t1\nweb\t0\n", false
+	})
+
+	out, err := c.Command("list-sessions", "-F", "#{session_name}\t#{session_attached}")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "api\t1\nweb\t0\n" {
+		t.Fatalf("output: got %q", out)
+	}
+	if _, err := c.Command("has-session", "-t", "gone"); err == nil || err.Error() != "can't find session: gone" {
+		t.Fatalf("expected tmux error, got %v", err)
+	}
+	if c.attachedSession() != "main" {
+		t.Fatalf("attached session: got %q", c.attachedSession())
+	}
+}
+
+func TestControlClientNotifications(t *testing.T) {
+	c := pipeControl(t, func(string) (string, bool) { return "", false })
+	if _, err := c.Command("refresh-client"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []Notification
+	for len(got) < 2 {
+		got = append(got, <-c.Notifications())
+	}
+	want := []Notification{
+		{Name: "session-changed", Args: []string{"$0", "main"}},
+		{Name: "window-add", Args: []string{"@3"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("notifications: got %+v want %+v", got, want)
+	}
+}
+
+func TestListSessionsDiscountsControlClient(t *testing.T) {
+	c := pipeControl(t, func(string) (string, bool) { return "main\t1\nweb\t2\n", false })
+	t.Cleanup(SetRunner(c))
+	t.Setenv("TMUX", "")
+
+	sessions, err := ListSessions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Session{{Name: "main"}, {Name: "web", Attached: true}}
+	if !reflect.DeepEqual(sessions, want) {
+		t.Fatalf("sessions: got %+v want %+v", sessions, want)
+	}
+}
+
+func TestControlClientOutputLooksLikeBlockEnd(t *testing.T) {
+	c := pipeControl(t, func(line string) (string, bool) {
+		if line == `'capture-pane' '-p'` {
+			return "%end 1 7 1\n%error 1 99 1\nreal\n", false
+		}
+		return "next\n", false
+	})
+
+	out, err := c.Command("capture-pane", "-p")
+	if err != nil || out != "%end 1 7 1\n%error 1 99 1\nreal\n" {
+		t.Fatalf("capture: got %q, %v", out, err)
+	}
+	if out, err := c.Command("display-message", "-p", "x"); err != nil || out != "next\n" {
+		t.Fatalf("next reply went astray: got %q, %v", out, err)
+	}
+}
@@ -1,14 +1,14 @@
 package tmux
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
-	"syscall"
 )
 
 // LaunchAction describes the outcome of a CreateSession call.
@@ -20,13 +20,39 @@ const (
 )
 
 // AttachToSession attaches to or switches to an existing session.
+// When the target names a window or pane, it is selected first.
 // Applies p's configuration to ensure consistent styling regardless of how the session was created.
-func AttachToSession(sessionName string) error {
+func AttachToSession(target Target) error {
+	sessionName := target.Session
+	if target.Server != (Server{}) {
+		restore := SetServer(target.Server)
+		defer restore()
+	}
+
 	// Configure the session before attaching (ensures consistent styling)
 	configureSession(sessionName)
 
+	if target.Window != "" {
+		windowTarget := Target{Session: sessionName, Window: target.Window}
+		if err := runTmuxQuiet("select-window", "-t", windowTarget.String()); err != nil {
+			return err
+		}
+		if target.Pane != "" {
+			if err := runTmuxQuiet("select-pane", "-t", target.String()); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Check if we're inside tmux
 	if os.Getenv("TMUX") != "" {
+		if !insideServer(server) {
+			return fmt.Errorf("cannot switch to %q on tmux server %s from a client of another server; detach first", sessionName, server)
+		}
+		// Already there: switching would only flash the client
+		if current, err := CurrentSession(); err == nil && current == sessionName {
+			return nil
+		}
 		// Inside tmux: switch client
 		return execTmux("switch-client", "-t", sessionName)
 	}
@@ -34,11 +60,12 @@ func AttachToSession(sessionName string) error {
 	return execTmux("attach-session", "-t", sessionName)
 }
 
-// CreateSession prepares a tmux session without attaching.
+// CreateSession prepares a tmux session without attaching, building the
+// windows described by layout.
 // Returns LaunchActionCreate when a new session was created, or
 // LaunchActionAttachExisting when a matching session already exists.
-func CreateSession(sessionName, workingDir string) (LaunchAction, error) {
-	if err := newDetachedSession(sessionName, workingDir); err != nil {
+func CreateSession(sessionName, workingDir string, layout Layout) (LaunchAction, error) {
+	if err := newDetachedSession(sessionName, workingDir, layout.Env); err != nil {
 		var dupErr *duplicateSessionError
 		if errors.As(err, &dupErr) {
 			matching, matchErr := sessionMatchesDirectory(sessionName, workingDir)
@@ -53,46 +80,24 @@ func CreateSession(sessionName, workingDir string) (LaunchAction, error) {
 		return "", err
 	}
 
-	configureSession(sessionName)
-	createDefaultWindows(sessionName, workingDir)
+	// A new session has nothing of its own to save; later windows are styled
+	// by the session hook as they are created.
+	accent := sessionAccent(sessionName, workingDir, "")
+	b := configureCommands(sessionName, []string{sessionName + ":^"}, accent)
+	b.add("set-option", "-t"+sessionName, savedOptionsOption, "{}")
+	b.add("set-option", "-t"+sessionName, configVersionOption, configVersion(sessionName, accent))
+	b = append(b, layoutCommands(sessionName, workingDir, layout)...)
+	runBatch(b)
 	return LaunchActionCreate, nil
 }
 
-// execTmux replaces the current process with tmux.
-func execTmux(args ...string) error {
-	tmuxPath, err := exec.LookPath("tmux")
-	if err != nil {
-		return err
-	}
-
-	fullArgs := append([]string{"tmux", "-f", "/dev/null"}, args...)
-	return syscall.Exec(tmuxPath, fullArgs, os.Environ())
-}
-
-// runTmux runs a tmux command and returns after completion.
-func runTmux(args ...string) error {
-	fullArgs := append([]string{"-f", "/dev/null"}, args...)
-	cmd := exec.Command("tmux", fullArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
-func newDetachedSession(sessionName, workingDir string) error {
-	args := []string{"-f", "/dev/null", "new-session", "-d", "-s", sessionName, "-c", workingDir}
-	cmd := exec.Command("tmux", args...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	cmd.Stdout = os.Stdout
-	if err := cmd.Run(); err != nil {
-		if strings.Contains(stderr.String(), "duplicate session") {
+func newDetachedSession(sessionName, workingDir string, env map[string]string) error {
+	args := append([]string{"new-session", "-d", "-s", sessionName, "-c", workingDir}, envFlags(env)...)
+	if _, err := runner.Run(args...); err != nil {
+		if strings.Contains(err.Error(), "duplicate session") {
 			return &duplicateSessionError{sessionName: sessionName}
 		}
-		msg := strings.TrimSpace(stderr.String())
-		if msg == "" {
-			msg = err.Error()
-		}
-		return fmt.Errorf("failed to create session: %s", msg)
+		return fmt.Errorf("failed to create session: %v", err)
 	}
 	return nil
 }
@@ -161,78 +166,160 @@ func fingerprintPath(p string) (pathFingerprint, error) {
 	return fp, nil
 }
 
-// createDefaultWindows creates default windows for a new session based on P_WINDOWS env var.
-// Default: home,cmd
-func createDefaultWindows(sessionName, workingDir string) {
-	// Get window names from env or use default
-	windowsEnv := os.Getenv("P_WINDOWS")
-	if windowsEnv == "" {
-		windowsEnv = "home,cmd"
-	}
+// configVersionOption is the session user option recording which version of
+// p's configuration was last applied.
+const configVersionOption = "@p_config_version"
 
-	windows := strings.Split(windowsEnv, ",")
-	if len(windows) == 0 {
+var forceConfigure bool
+
+// SetForceConfigure makes AttachToSession re-apply p's configuration even to
+// sessions already stamped with the current version. It returns a function
+// that restores the previous setting.
+func SetForceConfigure(force bool) (restore func()) {
+	prev := forceConfigure
+	forceConfigure = force
+	return func() { forceConfigure = prev }
+}
+
+// configureSession injects p's configuration into a session in one tmux call.
+// Sessions stamped with the current configuration version are left alone.
+// The first time a session is configured, the values p is about to override
+// are recorded so UnstyleSession can put them back.
+func configureSession(sessionName string) {
+	state, _ := tmuxOutput("display-message", "-p", "-t", sessionTarget(sessionName),
+		"#{session_path}\t#{"+accentOption+"}\t#{"+configVersionOption+"}")
+	fields := strings.SplitN(state, "\t", 3)
+	for len(fields) < 3 {
+		fields = append(fields, "")
+	}
+	path, pinned, applied := fields[0], fields[1], fields[2]
+	accent := sessionAccent(sessionName, path, pinned)
+	if !forceConfigure && applied == configVersion(sessionName, accent) {
 		return
 	}
+	windows, _ := ListWindows(sessionName)
+	targets := make([]string, len(windows))
+	for i, w := range windows {
+		targets[i] = w.Target().String()
+	}
+	b := configureCommands(sessionName, targets, accent)
+	if slices.ContainsFunc(b, func(c []string) bool { return c[0] == "set-hook" }) {
+		b = append(b, inheritHookCommands(sessionName)...)
+	}
+	b = append(b, saveOptionsCommands(sessionName, windows)...)
+	b.add("set-option", "-t"+sessionName, configVersionOption, configVersion(sessionName, accent))
+	runBatch(b)
+}
 
-	target := "-t" + sessionName
+// configVersion hashes the configuration commands, so any change to what p
+// injects marks existing sessions as out of date.
+func configVersion(sessionName, accent string) string {
+	sum := sha256.Sum256([]byte(batchScript(configureCommands(sessionName, nil, accent))))
+	return hex.EncodeToString(sum[:6])
+}
 
-	// Rename first window (index 0)
-	runTmuxSilent("rename-window", target+":0", strings.TrimSpace(windows[0]))
+// option is a tmux option name and the value p sets it to.
+type option struct {
+	name  string
+	value string
+}
 
-	// Create additional windows
-	for i := 1; i < len(windows); i++ {
-		name := strings.TrimSpace(windows[i])
-		runTmuxSilent("new-window", target, "-c", workingDir, "-n", name)
+// sessionStyle lists the session options p injects.
+// Per ADR-005: color-agnostic status bar styling
+func sessionStyle(c statusConfig, accent string) []option {
+	// Reset status to single line with default format (repairs any corrupted status-format)
+	return []option{
+		{"status", "on"},
+		{"status-format[0]", "#[align=left range=left #{E:status-left-style}]#[push-default]#{T;=/#{status-left-length}:status-left}#[pop-default]#[norange default]#[list=on align=#{status-justify}]#[list=left-marker]<#[list=right-marker]>#[list=on]#{W:#[range=window|#{window_index} #{E:window-status-style}#{?#{&&:#{window_last_flag},#{!=:#{E:window-status-last-style},default}}, #{E:window-status-last-style},}#{?#{&&:#{window_bell_flag},#{!=:#{E:window-status-bell-style},default}}, #{E:window-status-bell-style},#{?#{&&:#{||:#{window_activity_flag},#{window_silence_flag}},#{!=:#{E:window-status-activity-style},default}}, #{E:window-status-activity-style},}}]#[push-default]#{T:window-status-format}#[pop-default]#[norange default]#{?window_end_flag,,#{window-status-separator}},#[range=window|#{window_index} list=focus #{?#{!=:#{E:window-status-current-style},default},#{E:window-status-current-style},#{E:window-status-style}}#{?#{&&:#{window_last_flag},#{!=:#{E:window-status-last-style},default}}, #{E:window-status-last-style},}#{?#{&&:#{window_bell_flag},#{!=:#{E:window-status-bell-style},default}}, #{E:window-status-bell-style},#{?#{&&:#{||:#{window_activity_flag},#{window_silence_flag}},#{!=:#{E:window-status-activity-style},default}}, #{E:window-status-activity-style},}}]#[push-default]#{T:window-status-current-format}#[pop-default]#[norange default]#{?window_end_flag,,#{window-status-separator}}}#[nolist align=right range=right #{E:status-right-style}]#[push-default]#{T;=/#{status-right-length}:status-right}#[pop-default]#[norange default]"},
+		{"status-style", c.theme.status},
+		{"status-left-length", c.length},
+		{"status-right-length", c.length},
+		{"status-left", "#[" + c.accentStyle(accent) + "]" + c.left + " "},
+		{"status-right", "#[" + c.accentStyle(accent) + "]" + c.right + " "},
+		{"status-interval", "5"},
 	}
+}
+
+// windowStyle lists the window options p injects into each of the session's
+// windows.
+func windowStyle(c statusConfig) []option {
+	return []option{
+		// Vi-style copy mode (ADR-002)
+		{"mode-keys", "vi"},
+		// Pane background (pure black in the default theme)
+		{"window-style", c.theme.pane},
+		{"window-active-style", c.theme.pane},
+		// Remove pane borders entirely
+		{"pane-border-status", "off"},
+		// Window status styling with spacing
+		{"window-status-separator", "  "},
+		{"window-status-format", "#[" + c.theme.inactive + "] #I:#W "},
+		{"window-status-current-format", "#[" + c.theme.current + "] #I:#W "},
+	}
+}
 
-	// Select window 0
-	runTmuxSilent("select-window", target+":0")
+// copyModeBindings are the vi-style copy mode bindings p injects (ADR-002).
+// Key tables are server-wide; these only take effect in windows with
+// mode-keys vi.
+func copyModeBindings() [][]string {
+	return [][]string{
+		{"bind-key", "-T", "copy-mode-vi", "v", "send-keys", "-X", "begin-selection"},
+		{"bind-key", "-T", "copy-mode-vi", "y", "send-keys", "-X", "copy-selection-and-cancel"},
+	}
 }
 
-// configureSession injects minimal ergonomic defaults into a tmux session.
+// configureCommands injects minimal ergonomic defaults into a tmux session.
+// A non-empty accent colours the status segments instead of the theme.
+// Options are scoped to the session and to the given windows; a session hook
+// styles windows created later. Nothing is set globally, so other sessions
+// on the server keep their look. When P_TMUX_CONFIG loads a configuration,
+// whatever it sets takes precedence over p's defaults.
 // Per ADR-002: vi-style copy mode bindings
 // Per ADR-005: color-agnostic status bar styling
-func configureSession(sessionName string) {
+func configureCommands(sessionName string, windows []string, accent string) batch {
+	return layeredConfigureCommands(sessionName, windows, accent, loadUserSettings())
+}
+
+// layeredConfigureCommands is configureCommands beneath the given user
+// configuration.
+func layeredConfigureCommands(sessionName string, windows []string, accent string, user userSettings) batch {
+	var b batch
 	target := "-t" + sessionName
 
-	// Vi-style copy mode (ADR-002)
-	runTmuxSilent("set-option", target, "mode-keys", "vi")
-	runTmuxSilent("bind-key", "-T", "copy-mode-vi", "v", "send-keys", "-X", "begin-selection")
-	runTmuxSilent("bind-key", "-T", "copy-mode-vi", "y", "send-keys", "-X", "copy-selection-and-cancel")
+	status := currentStatusConfig()
+	b = append(b, layerBindings(copyModeBindings(), user)...)
+	for _, o := range layerOptions(sessionStyle(status, accent), user) {
+		b.add("set-option", target, o.name, o.value)
+	}
+	if accent != "" {
+		b.add("set-option", target, accentColourOption, accent)
+	} else {
+		b.add("set-option", "-u", target, accentColourOption)
+	}
+	windowOptions := layerOptions(windowStyle(status), user)
+	if len(windowOptions) == 0 {
+		return b
+	}
+	for _, w := range windows {
+		for _, o := range windowOptions {
+			b.add("set-option", "-w", "-t"+w, o.name, o.value)
+		}
+	}
+
+	var hook batch
+	for _, o := range windowOptions {
+		hook.add("set-option", "-w", o.name, o.value)
+	}
+	b.add("set-hook", target, newWindowHook, hookCommand(hook))
+	return b
+}
+
+// newWindowHook is the after-new-window entry p sets on a session. A session
+// hook hides the global one of the same name, so p uses an index of its own
+// and copies the global entries alongside it (see inheritHookCommands).
+const newWindowHook = "after-new-window[99]"
 
-	// Status bar styling (ADR-005) - Savvy AI aesthetic
-	// Dark gray background (colour235 ≈ #4e4e4e - matches Savvy AI navbar)
-	// Light gray foreground (colour240), sage green accent (colour108)
-	// Reset status to single line with default format (repairs any corrupted status-format)
-	runTmuxSilent("set-option", target, "status", "on")
-	runTmuxSilent("set-option", target, "status-format[0]", "#[align=left range=left #{E:status-left-style}]#[push-default]#{T;=/#{status-left-length}:status-left}#[pop-default]#[norange default]#[list=on align=#{status-justify}]#[list=left-marker]<#[list=right-marker]>#[list=on]#{W:#[range=window|#{window_index} #{E:window-status-style}#{?#{&&:#{window_last_flag},#{!=:#{E:window-status-last-style},default}}, #{E:window-status-last-style},}#{?#{&&:#{window_bell_flag},#{!=:#{E:window-status-bell-style},default}}, #{E:window-status-bell-style},#{?#{&&:#{||:#{window_activity_flag},#{window_silence_flag}},#{!=:#{E:window-status-activity-style},default}}, #{E:window-status-activity-style},}}]#[push-default]#{T:window-status-format}#[pop-default]#[norange default]#{?window_end_flag,,#{window-status-separator}},#[range=window|#{window_index} list=focus #{?#{!=:#{E:window-status-current-style},default},#{E:window-status-current-style},#{E:window-status-style}}#{?#{&&:#{window_last_flag},#{!=:#{E:window-status-last-style},default}}, #{E:window-status-last-style},}#{?#{&&:#{window_bell_flag},#{!=:#{E:window-status-bell-style},default}}, #{E:window-status-bell-style},#{?#{&&:#{||:#{window_activity_flag},#{window_silence_flag}},#{!=:#{E:window-status-activity-style},default}}, #{E:window-status-activity-style},}}]#[push-default]#{T:window-status-current-format}#[pop-default]#[norange default]#{?window_end_flag,,#{window-status-separator}}}#[nolist align=right range=right #{E:status-right-style}]#[push-default]#{T;=/#{status-right-length}:status-right}#[pop-default]#[norange default]")
-	runTmuxSilent("set-option", target, "status-style", "bg=colour235,fg=colour240")
-
-	// Pure black pane background (main interface)
-	// Use global options (-g) so all windows inherit the black background
-	runTmuxSilent("set-option", "-g", "window-style", "bg=colour16")
-	runTmuxSilent("set-option", "-g", "window-active-style", "bg=colour16")
-
-	// Remove pane borders entirely
-	runTmuxSilent("set-option", target, "pane-border-status", "off")
-	runTmuxSilent("set-option", target, "status-left-length", "40")
-	runTmuxSilent("set-option", target, "status-right-length", "40")
-	runTmuxSilent("set-option", target, "status-left", "#[fg=colour108][#S] ")
-	runTmuxSilent("set-option", target, "status-right", "#[fg=colour108]#(git -C #{pane_current_path} rev-parse --abbrev-ref HEAD 2>/dev/null) ")
-	runTmuxSilent("set-option", target, "status-interval", "5")
-
-	// Window status styling with spacing
-	runTmuxSilent("set-option", target, "window-status-separator", "  ")
-	runTmuxSilent("set-window-option", target, "window-status-format", "#[fg=colour240] #I:#W ")
-	runTmuxSilent("set-window-option", target, "window-status-current-format", "#[fg=white,bold] #I:#W ")
-
-}
-
-// runTmuxSilent runs a tmux command silently, ignoring errors.
-// Used for configuration where failure is non-fatal.
-func runTmuxSilent(args ...string) {
-	fullArgs := append([]string{"-f", "/dev/null"}, args...)
-	cmd := exec.Command("tmux", fullArgs...)
-	_ = cmd.Run()
+// hookCommand joins commands into one command string for set-hook.
+func hookCommand(b batch) string {
+	return strings.ReplaceAll(strings.TrimSuffix(batchScript(b), "\n"), "\n", " ; ")
 }
@@ -3,6 +3,7 @@ package tmux
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 )
 
@@ -31,3 +32,97 @@ func TestNormalizePathResolvesSymlinks(t *testing.T) {
 		t.Fatalf("paths differ: %q vs %q", targetFP.canonical, linkFP.canonical)
 	}
 }
+
+func TestTargetString(t *testing.T) {
+	tests := []struct {
+		target Target
+		want   string
+	}{
+		{Target{Session: "api"}, "api"},
+		{Target{Session: "api", Window: "2"}, "api:2"},
+		{Target{Session: "api", Window: "logs", Pane: "1"}, "api:logs.1"},
+		{Target{Session: "api", Pane: "1"}, "api"},
+	}
+	for _, tt := range tests {
+		if got := tt.target.String(); got != tt.want {
+			t.Fatalf("String(%+v): got %q want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Target
+		wantErr bool
+	}{
+		{"api", Target{Session: "api"}, false},
+		{"api:2", Target{Session: "api", Window: "2"}, false},
+		{"api:logs.1", Target{Session: "api", Window: "logs", Pane: "1"}, false},
+		{"api:v1.2-notes", Target{Session: "api", Window: "v1.2-notes"}, false},
+		{"", Target{}, true},
+		{":2", Target{}, true},
+		{"api:", Target{}, true},
+		{"api:.1", Target{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTarget(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Fatalf("ParseTarget(%q): expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("ParseTarget(%q): unexpected error: %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Fatalf("ParseTarget(%q): got %+v want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchWindow(t *testing.T) {
+	windows := []Window{
+		{Session: "api", Index: 0, Name: "home"},
+		{Session: "api", Index: 1, Name: "logs"},
+		{Session: "api", Index: 2, Name: "dup"},
+		{Session: "api", Index: 3, Name: "dup"},
+	}
+	if w, err := matchWindow(windows, "api", "1"); err != nil || w.Name != "logs" {
+		t.Fatalf("index match: got %+v, %v", w, err)
+	}
+	if w, err := matchWindow(windows, "api", "home"); err != nil || w.Index != 0 {
+		t.Fatalf("name match: got %+v, %v", w, err)
+	}
+	if _, err := matchWindow(windows, "api", "dup"); err == nil {
+		t.Fatalf("expected ambiguity error")
+	}
+	if _, err := matchWindow(windows, "api", "missing"); err == nil {
+		t.Fatalf("expected not-found error")
+	}
+}
+
+func TestServerSocketPath(t *testing.T) {
+	t.Setenv("TMUX_TMPDIR", "/run/t")
+	t.Setenv("TMUX", "")
+	uid := strconv.Itoa(os.Getuid())
+
+	if got, want := (Server{Name: "work"}).socketPath(), "/run/t/tmux-"+uid+"/work"; got != want {
+		t.Fatalf("named socket: got %q want %q", got, want)
+	}
+	if got, want := (Server{}).socketPath(), "/run/t/tmux-"+uid+"/default"; got != want {
+		t.Fatalf("default socket: got %q want %q", got, want)
+	}
+	if got := (Server{Socket: "/tmp/pair"}).socketPath(); got != "/tmp/pair" {
+		t.Fatalf("explicit socket: got %q", got)
+	}
+
+	t.Setenv("TMUX", "/run/t/tmux-"+uid+"/work,123,0")
+	if !insideServer(Server{Name: "work"}) || !insideServer(Server{}) {
+		t.Fatalf("expected to be inside the work server")
+	}
+	if insideServer(Server{Name: "personal"}) {
+		t.Fatalf("did not expect to be inside the personal server")
+	}
+}
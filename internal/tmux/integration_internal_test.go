@@ -0,0 +1,460 @@
+package tmux
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startIsolatedServer points the package at a private tmux server on a
+// temporary socket and kills it when the test ends. The developer's own
+// server is never touched.
+func startIsolatedServer(t *testing.T) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("skipping tmux integration test in short mode")
+	}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux not installed")
+	}
+	// Socket paths are length-limited, so avoid t.TempDir's long names.
+	dir, err := os.MkdirTemp("", "p-tmux-")
+	if err != nil {
+		t.Fatalf("socket dir: %v", err)
+	}
+	t.Setenv("TMUX", "")
+	restoreRunner := SetRunner(execRunner{})
+	restoreServer := SetServer(Server{Socket: filepath.Join(dir, "s")})
+	t.Cleanup(func() {
+		runTmuxSilent("kill-server")
+		restoreServer()
+		restoreRunner()
+		os.RemoveAll(dir)
+	})
+}
+
+func TestIntegrationSessionLifecycle(t *testing.T) {
+	startIsolatedServer(t)
+	t.Setenv("P_WINDOWS", "")
+
+	if _, err := ListSessions(); !IsNoServerError(err) {
+		t.Fatalf("expected no server before first session, got %v", err)
+	}
+
+	dir := t.TempDir()
+	action, err := CreateSession("itest", dir, testLayout)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if action != LaunchActionCreate {
+		t.Fatalf("action mismatch: got %q", action)
+	}
+
+	stamp, err := tmuxOutput("show-options", "-qv", "-t", "itest", configVersionOption)
+	if err != nil || stamp != configVersion("itest", "") {
+		t.Fatalf("config version stamp: got %q (%v)", stamp, err)
+	}
+
+	sessions, err := ListSessions()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if want := []Session{{Name: "itest", Server: server}}; !reflect.DeepEqual(sessions, want) {
+		t.Fatalf("sessions: got %+v want %+v", sessions, want)
+	}
+
+	path, err := GetSessionPath("itest")
+	if err != nil {
+		t.Fatalf("session path: %v", err)
+	}
+	matches, err := sessionMatchesDirectory("itest", dir)
+	if err != nil || !matches {
+		t.Fatalf("session path %q does not match %q (%v)", path, dir, err)
+	}
+
+	windows, err := ListWindows("itest")
+	if err != nil {
+		t.Fatalf("list windows: %v", err)
+	}
+	var names []string
+	for _, w := range windows {
+		names = append(names, w.Name)
+	}
+	if want := []string{"home", "cmd"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("windows: got %q want %q", names, want)
+	}
+
+	style, err := tmuxOutput("show-options", "-v", "-t", "itest", "status-style")
+	if err != nil {
+		t.Fatalf("show-options: %v", err)
+	}
+	if style != "bg=colour235,fg=colour240" {
+		t.Fatalf("status-style not configured: %q", style)
+	}
+	right, err := tmuxOutput("show-options", "-v", "-t", "itest", "status-right")
+	if err != nil {
+		t.Fatalf("show-options: %v", err)
+	}
+	if want := "#[fg=colour108]#(git -C #{pane_current_path} rev-parse --abbrev-ref HEAD 2>/dev/null)"; right != want {
+		t.Fatalf("status-right mangled by batching: got %q want %q", right, want)
+	}
+}
+
+func TestIntegrationDuplicateSession(t *testing.T) {
+	startIsolatedServer(t)
+
+	dir := t.TempDir()
+	if _, err := CreateSession("dup", dir, testLayout); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	action, err := CreateSession("dup", dir, testLayout)
+	if err != nil {
+		t.Fatalf("reuse: %v", err)
+	}
+	if action != LaunchActionAttachExisting {
+		t.Fatalf("expected reuse of same-directory session, got %q", action)
+	}
+
+	_, err = CreateSession("dup", t.TempDir(), testLayout)
+	var dupErr *duplicateSessionError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("expected duplicate session error for other directory, got %v", err)
+	}
+}
+
+func TestIntegrationControlClient(t *testing.T) {
+	startIsolatedServer(t)
+	t.Setenv("P_WINDOWS", "")
+
+	if _, err := DialControl(); err == nil {
+		t.Fatalf("expected dial to fail without a server")
+	}
+	if _, err := CreateSession("ctl", t.TempDir(), testLayout); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	client, err := DialControl()
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer client.Close()
+	restore := SetRunner(client)
+	defer restore()
+
+	sessions, err := ListSessions()
+	if err != nil {
+		t.Fatalf("list over control connection: %v", err)
+	}
+	if want := []Session{{Name: "ctl", Server: server}}; !reflect.DeepEqual(sessions, want) {
+		t.Fatalf("control client should not count as attached: got %+v", sessions)
+	}
+
+	if _, err := client.Command("has-session", "-t", "missing"); err == nil {
+		t.Fatalf("expected error for missing session")
+	}
+	if err := newDetachedSession("second", t.TempDir(), nil); err != nil {
+		t.Fatalf("create over control connection: %v", err)
+	}
+	for n := range client.Notifications() {
+		if n.Name == "sessions-changed" {
+			return
+		}
+	}
+	t.Fatalf("connection closed before %%sessions-changed")
+}
+
+func TestIntegrationStylingIsScopedAndReversible(t *testing.T) {
+	startIsolatedServer(t)
+
+	for _, name := range []string{"mine", "other"} {
+		if _, err := runner.Run("new-session", "-d", "-s", name); err != nil {
+			t.Fatalf("new-session %s: %v", name, err)
+		}
+	}
+	runTmuxSilent("set-option", "-t", "mine", "status-left", "custom ")
+	runTmuxSilent("set-option", "-w", "-t", "mine:0", "window-status-format", "#I")
+
+	configureSession("mine")
+	runTmuxSilent("new-window", "-d", "-t", "mine:")
+
+	option := func(args ...string) string {
+		t.Helper()
+		out, err := runner.Run(append([]string{"show-options", "-qv"}, args...)...)
+		if err != nil {
+			t.Fatalf("show-options %v: %v", args, err)
+		}
+		return strings.TrimSuffix(out, "\n")
+	}
+	if got := option("-g", "window-style"); got != "default" {
+		t.Fatalf("global window-style changed to %q", got)
+	}
+	if got := option("-w", "-t", "other:0", "window-style"); got != "" {
+		t.Fatalf("other session's window styled: %q", got)
+	}
+	for _, w := range []string{"mine:0", "mine:1"} {
+		if got := option("-w", "-t", w, "window-style"); got != "bg=colour16" {
+			t.Fatalf("%s window-style: got %q", w, got)
+		}
+	}
+
+	if err := UnstyleSession("mine"); err != nil {
+		t.Fatalf("unstyle: %v", err)
+	}
+	if got := option("-t", "mine", "status-left"); got != "custom " {
+		t.Fatalf("status-left not restored: %q", got)
+	}
+	if got := option("-w", "-t", "mine:0", "window-status-format"); got != "#I" {
+		t.Fatalf("window-status-format not restored: %q", got)
+	}
+	if got := option("-w", "-t", "mine:1", "window-style"); got != "" {
+		t.Fatalf("window-style left on new window: %q", got)
+	}
+	if got := option("-t", "mine", "status-style"); got != "" {
+		t.Fatalf("status-style left on session: %q", got)
+	}
+	if got := option("-t", "mine", configVersionOption); got != "" {
+		t.Fatalf("config version left on session: %q", got)
+	}
+}
+
+func TestIntegrationStylingKeepsUserHooks(t *testing.T) {
+	startIsolatedServer(t)
+
+	for _, name := range []string{"plain", "hooked"} {
+		if _, err := runner.Run("new-session", "-d", "-s", name); err != nil {
+			t.Fatalf("new-session %s: %v", name, err)
+		}
+	}
+	runTmuxSilent("set-hook", "-g", "after-new-window", "set-option -w @ran global")
+	runTmuxSilent("set-hook", "-t", "hooked", "after-new-window", "set-option -w @ran own")
+
+	for _, tc := range []struct{ session, want string }{{"plain", "global"}, {"hooked", "own"}} {
+		configureSession(tc.session)
+		runTmuxSilent("new-window", "-d", "-t", tc.session+":")
+		for option, want := range map[string]string{"@ran": tc.want, "window-style": "bg=colour16"} {
+			got, err := tmuxOutput("show-options", "-qv", "-w", "-t", tc.session+":1", option)
+			if err != nil || got != want {
+				t.Fatalf("%s %s: got %q (%v) want %q", tc.session, option, got, err, want)
+			}
+		}
+	}
+
+	for _, tc := range []struct{ session, want string }{
+		{"plain", ""},
+		{"hooked", "after-new-window[0] set-option -w @ran own"},
+	} {
+		if err := UnstyleSession(tc.session); err != nil {
+			t.Fatalf("unstyle %s: %v", tc.session, err)
+		}
+		got, err := tmuxOutput("show-hooks", "-t", tc.session, "after-new-window")
+		if err != nil || got != tc.want {
+			t.Fatalf("%s hooks after unstyle: got %q (%v) want %q", tc.session, got, err, tc.want)
+		}
+	}
+}
+
+func TestIntegrationUserConfigLayersOverDefaults(t *testing.T) {
+	conf := filepath.Join(t.TempDir(), "tmux.conf")
+	settings := "set -g prefix C-a\nset -g status-style bg=blue\n" +
+		"if-shell true 'set -g status-left if'\n" +
+		"run-shell 'tmux -S \"#{socket_path}\" set -g status-right run'\n"
+	if err := os.WriteFile(conf, []byte(settings), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("P_TMUX_CONFIG", conf)
+	startIsolatedServer(t)
+
+	if _, err := CreateSession("layered", t.TempDir(), testLayout); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for _, tc := range []struct{ scope, option, want string }{
+		{"-g", "prefix", "C-a"},
+		{"-g", "status-style", "bg=blue"},
+		{"-g", "status-left", "if"},
+		{"-g", "status-right", "run"},
+		// The user's global value shows through because p left it alone.
+		{"-tlayered", "status-style", ""},
+		{"-tlayered", "status-left", ""},
+		{"-tlayered", "status-right", ""},
+		{"-tlayered", "status-interval", "5"},
+	} {
+		got, err := tmuxOutput("show-options", "-qv", tc.scope, tc.option)
+		if err != nil || got != tc.want {
+			t.Fatalf("%s %s: got %q (%v) want %q", tc.scope, tc.option, got, err, tc.want)
+		}
+	}
+}
+
+func TestIntegrationWindowsWithPanesAndCommands(t *testing.T) {
+	startIsolatedServer(t)
+
+	windows, err := ParseWindows("editor=echo editing,logs@even-horizontal=echo left|echo right|")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	dir := t.TempDir()
+	if _, err := CreateSession("panes", dir, Layout{Windows: windows}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	got, err := ListWindows("panes")
+	if err != nil {
+		t.Fatalf("list windows: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "editor" || got[1].Name != "logs" || got[1].Panes != 3 || !got[0].Active {
+		t.Fatalf("windows: got %+v", got)
+	}
+	panes, err := ListPanes("panes", got[1].Index)
+	if err != nil {
+		t.Fatalf("list panes: %v", err)
+	}
+	for _, p := range panes {
+		if !sameDir(t, p.Path, dir) {
+			t.Fatalf("pane %d in %q, want %q", p.Index, p.Path, dir)
+		}
+	}
+	if !panes[0].Active {
+		t.Fatalf("expected the first pane to be active: %+v", panes)
+	}
+}
+
+func TestIntegrationLayoutEnvDirsAndFocus(t *testing.T) {
+	startIsolatedServer(t)
+
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "web")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	layout := Layout{
+		Focus: "web",
+		Env:   map[string]string{"P_TEST_PORT": "8080"},
+		Windows: []WindowSpec{
+			{Name: "home", Panes: []PaneSpec{{}}},
+			{Name: "web", Panes: []PaneSpec{{Dir: "web"}}},
+		},
+	}
+	if _, err := CreateSession("env", dir, layout); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	env, err := tmuxOutput("show-environment", "-t", "env", "P_TEST_PORT")
+	if err != nil || env != "P_TEST_PORT=8080" {
+		t.Fatalf("environment: got %q, %v", env, err)
+	}
+	windows, err := ListWindows("env")
+	if err != nil {
+		t.Fatalf("list windows: %v", err)
+	}
+	if len(windows) != 2 || !windows[1].Active {
+		t.Fatalf("expected the web window to be focused: %+v", windows)
+	}
+	panes, err := ListPanes("env", windows[1].Index)
+	if err != nil || len(panes) != 1 || !sameDir(t, panes[0].Path, sub) {
+		t.Fatalf("web pane: got %+v, %v", panes, err)
+	}
+}
+
+func TestIntegrationCaptureLayoutReplays(t *testing.T) {
+	startIsolatedServer(t)
+
+	dir := t.TempDir()
+	windows, err := ParseWindows("home,logs@main-vertical=|sleep 30|")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if _, err := CreateSession("orig", dir, Layout{Windows: windows, Focus: "logs"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	waitFor(t, func() bool {
+		panes, _ := ListPanes("orig", 1)
+		return len(panes) == 3 && panes[1].Command == "sleep"
+	})
+
+	captured, err := CaptureLayout("orig", true)
+	if err != nil {
+		t.Fatalf("capture: %v", err)
+	}
+	if captured.Focus != "logs" || len(captured.Windows) != 2 || len(captured.Windows[1].Panes) != 3 {
+		t.Fatalf("captured layout: %+v", captured)
+	}
+	logs := captured.Windows[1]
+	if logs.Panes[0].Command != "" || logs.Panes[1].Command != "sleep" || !sameDir(t, logs.Panes[0].Dir, dir) {
+		t.Fatalf("captured panes: %+v", logs.Panes)
+	}
+
+	if _, err := CreateSession("copy", dir, captured); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	copied, _ := ListWindows("copy")
+	if len(copied) != 2 || !copied[1].Active {
+		t.Fatalf("replayed windows: %+v", copied)
+	}
+	// Layout strings embed pane ids, so compare the panes' geometry.
+	geometry := func(session string) string {
+		out, _ := tmuxOutput("list-panes", "-t", session+":1", "-F", "#{pane_left},#{pane_top},#{pane_width},#{pane_height}")
+		return out
+	}
+	if got, want := geometry("copy"), geometry("orig"); got != want {
+		t.Fatalf("replayed panes:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestIntegrationSessionsInDirectoryAndKill(t *testing.T) {
+	startIsolatedServer(t)
+	t.Setenv("P_WINDOWS", "")
+
+	if names, err := SessionsInDirectory(t.TempDir()); err != nil || names != nil {
+		t.Fatalf("without a server: %q, %v", names, err)
+	}
+	dir, other := t.TempDir(), t.TempDir()
+	for name, d := range map[string]string{"a": dir, "b": dir, "c": other} {
+		if _, err := CreateSession(name, d, testLayout); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+	}
+	// "c" is also a prefix of the cmd windows; it must still mean session c.
+	for range 5 {
+		if path, err := GetSessionPath("c"); err != nil || !sameDir(t, path, other) {
+			t.Fatalf("path of c: %q, %v", path, err)
+		}
+	}
+	names, err := SessionsInDirectory(dir)
+	if err != nil || !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Fatalf("sessions in dir: %q, %v", names, err)
+	}
+	if err := KillSession("a"); err != nil {
+		t.Fatalf("kill: %v", err)
+	}
+	if names, _ := SessionsInDirectory(dir); !reflect.DeepEqual(names, []string{"b"}) {
+		t.Fatalf("after kill: %q", names)
+	}
+	if err := KillSession("a"); err == nil {
+		t.Fatalf("expected an error killing a missing session")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	// Shells can take a while to start and run what was typed.
+	for range 500 {
+		if cond() {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("condition not met")
+}
+
+func sameDir(t *testing.T, a, b string) bool {
+	t.Helper()
+	ra, errA := filepath.EvalSymlinks(a)
+	rb, errB := filepath.EvalSymlinks(b)
+	return errA == nil && errB == nil && ra == rb
+}
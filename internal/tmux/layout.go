@@ -0,0 +1,291 @@
+package tmux
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Layout describes the windows of a new session.
+type Layout struct {
+	Windows []WindowSpec
+	// Focus names the window selected once the session is built; empty
+	// selects the first.
+	Focus string
+	// Env is set in the session's environment, so every pane sees it.
+	Env map[string]string
+}
+
+// WindowSpec describes one window: its name, how its panes are arranged and
+// what each pane runs.
+type WindowSpec struct {
+	Name string
+	// Arrangement is a tmux layout for the window's panes: a preset such as
+	// even-horizontal, or a layout string from list-windows. Empty means
+	// tiled when the window has several panes.
+	Arrangement string
+	// Panes has one entry per pane; a window always has at least one.
+	Panes []PaneSpec
+}
+
+// PaneSpec describes one pane of a window.
+type PaneSpec struct {
+	// Command is typed into the pane's shell once it starts. Empty leaves a
+	// plain shell.
+	Command string
+	// Dir is the pane's working directory, relative to the session's
+	// directory unless absolute. Empty means the session's directory.
+	Dir string
+}
+
+// defaultWindows is used when P_WINDOWS is unset (ADR-009).
+const defaultWindows = "home,cmd"
+
+// defaultArrangement spreads several panes evenly.
+const defaultArrangement = "tiled"
+
+// layoutPresets are tmux's built-in layouts.
+var layoutPresets = []string{"even-horizontal", "even-vertical", "main-horizontal", "main-vertical", "tiled"}
+
+// layoutStringPattern matches layout strings as printed by #{window_layout}.
+var layoutStringPattern = regexp.MustCompile(`^[0-9a-f]{4},\d+x\d+`)
+
+// DefaultLayout parses P_WINDOWS, falling back to home,cmd.
+func DefaultLayout() (Layout, error) {
+	value := os.Getenv("P_WINDOWS")
+	if strings.TrimSpace(value) == "" {
+		value = defaultWindows
+	}
+	windows, err := ParseWindows(value)
+	if err != nil {
+		return Layout{}, fmt.Errorf("P_WINDOWS: %w", err)
+	}
+	if len(windows) == 0 {
+		windows, _ = ParseWindows(defaultWindows)
+	}
+	return Layout{Windows: windows}, nil
+}
+
+// ParseWindows parses the P_WINDOWS syntax: comma-separated windows, each
+// name[@layout][=command[|command...]]. Every command after = gets its own
+// pane; an empty command leaves a plain shell. A backslash escapes a
+// literal comma, pipe or backslash. Blank entries are ignored.
+//
+//	editor=nvim .,server=npm run dev,logs@even-horizontal=tail -f log/dev.log|htop
+func ParseWindows(s string) ([]WindowSpec, error) {
+	var windows []WindowSpec
+	for _, entry := range splitEscaped(s, ',') {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		w, err := parseWindow(entry)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+func parseWindow(entry string) (WindowSpec, error) {
+	head, commands, hasCommands := cutUnescaped(entry, '=')
+	name, arrangement, _ := strings.Cut(head, "@")
+	w := WindowSpec{Name: strings.TrimSpace(unescape(name)), Arrangement: strings.TrimSpace(arrangement)}
+	if w.Name == "" {
+		return WindowSpec{}, fmt.Errorf("window %q has no name", strings.TrimSpace(entry))
+	}
+	if err := CheckArrangement(w.Arrangement); err != nil {
+		return WindowSpec{}, fmt.Errorf("window %q: %w", w.Name, err)
+	}
+	if !hasCommands {
+		w.Panes = []PaneSpec{{}}
+		return w, nil
+	}
+	for _, cmd := range splitEscaped(commands, '|') {
+		w.Panes = append(w.Panes, PaneSpec{Command: strings.TrimSpace(unescape(cmd))})
+	}
+	return w, nil
+}
+
+// CheckArrangement reports a window layout that is neither a tmux preset nor
+// a layout string.
+func CheckArrangement(arrangement string) error {
+	if arrangement == "" || layoutStringPattern.MatchString(arrangement) {
+		return nil
+	}
+	for _, preset := range layoutPresets {
+		if arrangement == preset {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown layout %q (use %s)", arrangement, strings.Join(layoutPresets, ", "))
+}
+
+// splitEscaped splits s at sep, skipping separators preceded by a
+// backslash. Escapes are kept for the caller to remove.
+func splitEscaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// cutUnescaped is strings.Cut honouring backslash escapes.
+func cutUnescaped(s string, sep byte) (before, after string, found bool) {
+	parts := splitEscaped(s, sep)
+	if len(parts) == 1 {
+		return s, "", false
+	}
+	return parts[0], s[len(parts[0])+1:], true
+}
+
+// unescape removes the backslash from escape sequences.
+func unescape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// layoutCommands builds the windows of a new session. The session's first
+// window is renamed to the first spec; later windows are appended, each
+// addressed as the session's last window right after it is created.
+// The first window's first pane already exists, so a directory given for it
+// is entered with cd.
+func layoutCommands(sessionName, workingDir string, layout Layout) batch {
+	var b batch
+	for i, w := range layout.Windows {
+		target := sessionName + ":$"
+		if i == 0 {
+			target = sessionName + ":^"
+			b.add("rename-window", "-t"+target, w.Name)
+			if len(w.Panes) > 0 && w.Panes[0].Dir != "" {
+				b.add("send-keys", "-t"+target, "cd "+shellQuote(paneDir(workingDir, w.Panes[0])), "Enter")
+			}
+		} else {
+			b.add("new-window", "-t"+sessionName+":", "-c", paneDir(workingDir, firstPane(w)), "-n", w.Name)
+		}
+		for j, p := range w.Panes {
+			if j > 0 {
+				// The new pane becomes active, so -t target reaches it below.
+				// Re-tiling after each split leaves room for the next one.
+				b.add("split-window", "-t"+target, "-c", paneDir(workingDir, p))
+				b.add("select-layout", "-t"+target, defaultArrangement)
+			}
+			if p.Command != "" {
+				b.add("send-keys", "-t"+target, p.Command, "Enter")
+			}
+		}
+		if len(w.Panes) > 1 {
+			arrangement := w.Arrangement
+			if arrangement == "" {
+				arrangement = defaultArrangement
+			}
+			b.add("select-layout", "-t"+target, arrangement)
+			b.add("select-pane", "-t"+target+".{top-left}")
+		}
+	}
+	switch {
+	case layout.Focus != "":
+		b.add("select-window", "-t"+sessionName+":="+layout.Focus)
+	case len(layout.Windows) > 0:
+		b.add("select-window", "-t"+sessionName+":^")
+	}
+	return b
+}
+
+func firstPane(w WindowSpec) PaneSpec {
+	if len(w.Panes) == 0 {
+		return PaneSpec{}
+	}
+	return w.Panes[0]
+}
+
+// paneDir resolves a pane's directory against the session's.
+func paneDir(workingDir string, p PaneSpec) string {
+	switch {
+	case p.Dir == "":
+		return workingDir
+	case filepath.IsAbs(p.Dir):
+		return p.Dir
+	default:
+		return filepath.Join(workingDir, p.Dir)
+	}
+}
+
+// shellQuote single-quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// envFlags renders the layout's environment as new-session -e flags, in a
+// stable order.
+func envFlags(env map[string]string) []string {
+	var flags []string
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		flags = append(flags, "-e", key+"="+env[key])
+	}
+	return flags
+}
+
+// shells are commands treated as a pane's idle shell when capturing a
+// layout, alongside the server's default-shell.
+var shells = []string{"sh", "bash", "zsh", "fish", "dash", "ksh", "tcsh", "csh", "nu"}
+
+// CaptureLayout reads the windows and panes of a running session, with
+// each pane's directory and the active window as focus. With commands,
+// panes record the program running in them; only its name is known, not
+// its arguments, and idle shells are left out.
+func CaptureLayout(sessionName string, commands bool) (Layout, error) {
+	windows, err := ListWindows(sessionName)
+	if err != nil {
+		return Layout{}, err
+	}
+	idle := map[string]bool{}
+	for _, s := range shells {
+		idle[s] = true
+	}
+	if shell, err := tmuxOutput("show-options", "-gqv", "default-shell"); err == nil && shell != "" {
+		idle[filepath.Base(shell)] = true
+	}
+
+	var layout Layout
+	for _, w := range windows {
+		panes, err := ListPanes(sessionName, w.Index)
+		if err != nil {
+			return Layout{}, err
+		}
+		spec := WindowSpec{Name: w.Name}
+		if len(panes) > 1 {
+			spec.Arrangement = w.Layout
+		}
+		for _, p := range panes {
+			pane := PaneSpec{Dir: p.Path}
+			if commands && !idle[p.Command] {
+				pane.Command = p.Command
+			}
+			spec.Panes = append(spec.Panes, pane)
+		}
+		if w.Active {
+			layout.Focus = w.Name
+		}
+		layout.Windows = append(layout.Windows, spec)
+	}
+	return layout, nil
+}
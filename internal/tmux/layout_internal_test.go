@@ -0,0 +1,103 @@
+package tmux
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseWindows(t *testing.T) {
+	got, err := ParseWindows(`editor=nvim .,, server = npm run dev ,logs@even-horizontal=tail -f log/dev.log|htop,split=|,calc=echo 1\,2 \| wc`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []WindowSpec{
+		{Name: "editor", Panes: []PaneSpec{{Command: "nvim ."}}},
+		{Name: "server", Panes: []PaneSpec{{Command: "npm run dev"}}},
+		{Name: "logs", Arrangement: "even-horizontal", Panes: []PaneSpec{{Command: "tail -f log/dev.log"}, {Command: "htop"}}},
+		{Name: "split", Panes: []PaneSpec{{}, {}}},
+		{Name: "calc", Panes: []PaneSpec{{Command: "echo 1,2 | wc"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("windows:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseWindowsErrors(t *testing.T) {
+	for input, want := range map[string]string{
+		"home,=htop":          `window "=htop" has no name`,
+		"home,logs@grid=htop": `window "logs": unknown layout "grid"`,
+		"@tiled=a|b":          "has no name",
+	} {
+		if _, err := ParseWindows(input); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q: expected %q, got %v", input, want, err)
+		}
+	}
+}
+
+func TestDefaultLayout(t *testing.T) {
+	t.Setenv("P_WINDOWS", "")
+	layout, err := DefaultLayout()
+	if err != nil || !reflect.DeepEqual(layout, testLayout) {
+		t.Fatalf("default: got %+v, %v", layout, err)
+	}
+	t.Setenv("P_WINDOWS", "a@bogus")
+	if _, err := DefaultLayout(); err == nil || !strings.HasPrefix(err.Error(), "P_WINDOWS: ") {
+		t.Fatalf("expected P_WINDOWS error, got %v", err)
+	}
+}
+
+func TestLayoutCommands(t *testing.T) {
+	layout := Layout{Windows: []WindowSpec{
+		{Name: "editor", Panes: []PaneSpec{{Command: "nvim ."}}},
+		{Name: "logs", Arrangement: "main-vertical", Panes: []PaneSpec{{Command: "tail -f x"}, {}}},
+	}}
+	want := batch{
+		{"rename-window", "-tapi:^", "editor"},
+		{"send-keys", "-tapi:^", "nvim .", "Enter"},
+		{"new-window", "-tapi:", "-c", "/work/api", "-n", "logs"},
+		{"send-keys", "-tapi:$", "tail -f x", "Enter"},
+		{"split-window", "-tapi:$", "-c", "/work/api"},
+		{"select-layout", "-tapi:$", "tiled"},
+		{"select-layout", "-tapi:$", "main-vertical"},
+		{"select-pane", "-tapi:$.{top-left}"},
+		{"select-window", "-tapi:^"},
+	}
+	if got := layoutCommands("api", "/work/api", layout); !reflect.DeepEqual(got, want) {
+		t.Fatalf("commands:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestLayoutCommandsDirsAndFocus(t *testing.T) {
+	layout := Layout{
+		Focus: "web",
+		Windows: []WindowSpec{
+			{Name: "editor", Panes: []PaneSpec{{Dir: "src"}}},
+			{Name: "web", Panes: []PaneSpec{{Dir: "/srv/web"}, {Dir: "it's"}}},
+		},
+	}
+	want := batch{
+		{"rename-window", "-tapi:^", "editor"},
+		{"send-keys", "-tapi:^", "cd '/work/api/src'", "Enter"},
+		{"new-window", "-tapi:", "-c", "/srv/web", "-n", "web"},
+		{"split-window", "-tapi:$", "-c", "/work/api/it's"},
+		{"select-layout", "-tapi:$", "tiled"},
+		{"select-layout", "-tapi:$", "tiled"},
+		{"select-pane", "-tapi:$.{top-left}"},
+		{"select-window", "-tapi:=web"},
+	}
+	if got := layoutCommands("api", "/work/api", layout); !reflect.DeepEqual(got, want) {
+		t.Fatalf("commands:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestEnvFlags(t *testing.T) {
+	got := envFlags(map[string]string{"PORT": "8080", "APP": "api"})
+	want := []string{"-e", "APP=api", "-e", "PORT=8080"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	if envFlags(nil) != nil {
+		t.Fatalf("expected no flags without an environment")
+	}
+}
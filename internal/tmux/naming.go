@@ -0,0 +1,131 @@
+package tmux
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// SanitizeName makes a session name safe for tmux, which treats . and : as
+// target separators and rewrites them itself. Both, along with control
+// characters, become underscores.
+func SanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == ':' || unicode.IsControl(r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+}
+
+// CollisionPolicy decides the name of a session for a directory when a
+// session of the same name is open in another directory.
+type CollisionPolicy string
+
+// Collision policies for P_COLLISION.
+const (
+	// CollisionHash appends a short hash of the directory (ADR-003).
+	CollisionHash CollisionPolicy = "hash"
+	// CollisionParent prefixes the parent directory's name, e.g. work/api.
+	CollisionParent CollisionPolicy = "parent"
+	// CollisionPrompt asks for a name.
+	CollisionPrompt CollisionPolicy = "prompt"
+	// CollisionError refuses to create the session.
+	CollisionError CollisionPolicy = "error"
+)
+
+// LoadCollisionPolicy reads P_COLLISION, defaulting to hash.
+func LoadCollisionPolicy() (CollisionPolicy, error) {
+	value := CollisionPolicy(strings.TrimSpace(os.Getenv("P_COLLISION")))
+	switch value {
+	case "":
+		return CollisionHash, nil
+	case CollisionHash, CollisionParent, CollisionPrompt, CollisionError:
+		return value, nil
+	}
+	return "", fmt.Errorf("P_COLLISION: %q is not hash, parent, prompt or error", value)
+}
+
+// NameCollisionError reports a session name taken by a session in another
+// directory. Suggestion is a free name for the directory.
+type NameCollisionError struct {
+	Name       string
+	Dir        string
+	Existing   string
+	Suggestion string
+}
+
+func (e *NameCollisionError) Error() string {
+	return fmt.Sprintf("session %q is already open in %s (set P_COLLISION=hash, parent or prompt to pick another name)", e.Name, e.Existing)
+}
+
+// SessionName returns the name for a session in dir, starting from name.
+// The name is kept when it is free or its session is already in dir, so
+// that session is reused. Otherwise the policy picks another name, which is
+// itself kept only when free or reused. Prompt and error policies return a
+// *NameCollisionError.
+func SessionName(name, dir string, policy CollisionPolicy) (string, error) {
+	existing, err := sessionDirectory(name)
+	if err != nil || existing == "" {
+		return name, err
+	}
+	if matching, err := sessionMatchesDirectory(name, dir); err != nil || matching {
+		return name, err
+	}
+
+	candidates := []string{hashedName(name, dir)}
+	if policy == CollisionParent {
+		parent := SanitizeName(filepath.Base(filepath.Dir(dir)))
+		candidates = []string{parent + "/" + name, hashedName(parent+"/"+name, dir)}
+	}
+	var suggestion string
+	for _, candidate := range candidates {
+		taken, err := sessionDirectory(candidate)
+		if err != nil {
+			return "", err
+		}
+		if taken == "" {
+			suggestion = candidate
+			break
+		}
+		if matching, err := sessionMatchesDirectory(candidate, dir); err != nil || matching {
+			return candidate, err
+		}
+	}
+	if suggestion == "" || policy == CollisionPrompt || policy == CollisionError {
+		return "", &NameCollisionError{Name: name, Dir: dir, Existing: existing, Suggestion: suggestion}
+	}
+	return suggestion, nil
+}
+
+// NameAvailable reports whether a session called name could be created
+// for dir: no session has the name, or its session is in dir.
+func NameAvailable(name, dir string) (bool, error) {
+	existing, err := sessionDirectory(name)
+	if err != nil || existing == "" {
+		return err == nil, err
+	}
+	return sessionMatchesDirectory(name, dir)
+}
+
+// sessionDirectory returns the directory of the session called exactly
+// name, or "" when there is none. Both the check and the lookup use exact
+// targets, so a window or another session whose name starts with name is
+// never read instead.
+func sessionDirectory(name string) (string, error) {
+	if _, err := runner.Run("has-session", "-t", "="+name); err != nil {
+		return "", nil
+	}
+	return GetSessionPath(name)
+}
+
+// hashedName suffixes name with six hex digits of the directory's hash,
+// stable for the same path.
+func hashedName(name, dir string) string {
+	sum := sha256.Sum256([]byte(dir))
+	return name + "-" + hex.EncodeToString(sum[:3])
+}
@@ -0,0 +1,113 @@
+package tmux
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := map[string]string{
+		"api":         "api",
+		"example.com": "example_com",
+		"a:b.c":       "a_b_c",
+		" spaced ":    "spaced",
+		"tab\tname":   "tab_name",
+		"work/api":    "work/api",
+	}
+	for in, want := range tests {
+		if got := SanitizeName(in); got != want {
+			t.Errorf("SanitizeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLoadCollisionPolicy(t *testing.T) {
+	t.Setenv("P_COLLISION", "")
+	if p, err := LoadCollisionPolicy(); err != nil || p != CollisionHash {
+		t.Fatalf("default = %q, %v", p, err)
+	}
+	t.Setenv("P_COLLISION", "parent")
+	if p, err := LoadCollisionPolicy(); err != nil || p != CollisionParent {
+		t.Fatalf("parent = %q, %v", p, err)
+	}
+	t.Setenv("P_COLLISION", "rename")
+	if _, err := LoadCollisionPolicy(); err == nil {
+		t.Fatalf("expected error for unknown policy")
+	}
+}
+
+func TestHashedNameIsStable(t *testing.T) {
+	a, b := hashedName("api", "/work/api"), hashedName("api", "/personal/api")
+	if a != hashedName("api", "/work/api") || a == b || len(a) != len("api-")+6 {
+		t.Fatalf("hashed names: %q, %q", a, b)
+	}
+}
+
+func TestIntegrationSessionNameCollisions(t *testing.T) {
+	startIsolatedServer(t)
+	t.Setenv("P_WINDOWS", "")
+
+	root := t.TempDir()
+	work, personal := filepath.Join(root, "work", "api"), filepath.Join(root, "personal", "api")
+	for _, dir := range []string{work, personal} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := CreateSession("api", work, testLayout); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if name, err := SessionName("api", work, CollisionHash); err != nil || name != "api" {
+		t.Fatalf("same directory should reuse the session, got %q, %v", name, err)
+	}
+	hashed := hashedName("api", personal)
+	if name, err := SessionName("api", personal, CollisionHash); err != nil || name != hashed {
+		t.Fatalf("hash policy = %q, %v; want %q", name, err, hashed)
+	}
+	if name, err := SessionName("api", personal, CollisionParent); err != nil || name != "personal/api" {
+		t.Fatalf("parent policy = %q, %v", name, err)
+	}
+	var collision *NameCollisionError
+	_, err := SessionName("api", personal, CollisionError)
+	if !errors.As(err, &collision) || collision.Suggestion != hashed || !sameDir(t, collision.Existing, work) {
+		t.Fatalf("error policy = %v (%+v)", err, collision)
+	}
+
+	if _, err := CreateSession(hashed, personal, testLayout); err != nil {
+		t.Fatalf("create hashed: %v", err)
+	}
+	for _, policy := range []CollisionPolicy{CollisionHash, CollisionError} {
+		if name, err := SessionName("api", personal, policy); err != nil || name != hashed {
+			t.Fatalf("%s policy should reuse %q, got %q, %v", policy, hashed, name, err)
+		}
+	}
+	if ok, err := NameAvailable("api", personal); err != nil || ok {
+		t.Fatalf("api should be taken for %s: %v, %v", personal, ok, err)
+	}
+	if _, err := CreateSession("personal/api", personal, testLayout); err != nil {
+		t.Fatalf("names with a slash should work: %v", err)
+	}
+}
+
+func TestIntegrationSessionNameReadsTheNamedSession(t *testing.T) {
+	startIsolatedServer(t)
+	t.Setenv("P_WINDOWS", "")
+
+	dir, other := t.TempDir(), t.TempDir()
+	// Session a has a window cmd, which "c" is a prefix of.
+	if _, err := CreateSession("a", dir, testLayout); err != nil {
+		t.Fatalf("create a: %v", err)
+	}
+	if _, err := CreateSession("c", other, testLayout); err != nil {
+		t.Fatalf("create c: %v", err)
+	}
+	hashed := hashedName("c", dir)
+	for range 5 {
+		if name, err := SessionName("c", dir, CollisionHash); err != nil || name != hashed {
+			t.Fatalf("c is open in another directory: got %q, %v; want %q", name, err, hashed)
+		}
+	}
+}
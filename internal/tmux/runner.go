@@ -0,0 +1,173 @@
+package tmux
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/wilmoore/p/internal/clierr"
+)
+
+// Runner executes tmux commands. Every call in this package goes through the
+// active runner, so tests can record the exact command sequence and simulate
+// failures without a tmux server.
+type Runner interface {
+	// Run runs a tmux command to completion and returns its stdout.
+	// Failures are reported as *CommandError.
+	Run(args ...string) (string, error)
+	// Batch runs several tmux commands in a single tmux process. Every
+	// command runs even when an earlier one fails; failures are reported
+	// together as one *CommandError.
+	Batch(commands [][]string) error
+	// Exec replaces the current process with tmux.
+	Exec(args ...string) error
+}
+
+// CommandError describes a tmux command that exited unsuccessfully.
+type CommandError struct {
+	Args   []string
+	Stderr string
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	if e.Stderr != "" {
+		return e.Stderr
+	}
+	return e.Err.Error()
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+var runner Runner = execRunner{}
+
+// SetRunner replaces the package's runner and returns a function that
+// restores the previous one. The tmux binary is used by default.
+func SetRunner(r Runner) (restore func()) {
+	prev := runner
+	runner = r
+	return func() { runner = prev }
+}
+
+// execRunner runs the tmux binary against the selected server, with -f
+// /dev/null (ADR-002) unless P_TMUX_CONFIG opts in to a configuration.
+type execRunner struct{}
+
+// tmuxArgs prefixes args with the global flags for every tmux invocation.
+func tmuxArgs(args ...string) []string {
+	full := append(configFlags(), server.flags()...)
+	return append(full, args...)
+}
+
+func (execRunner) Run(args ...string) (string, error) {
+	// -u keeps tmux from rewriting tab separators in formats on non-UTF-8 locales.
+	cmd := exec.Command("tmux", append([]string{"-u"}, tmuxArgs(args...)...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", &CommandError{Args: args, Stderr: strings.TrimSpace(stderr.String()), Err: err}
+	}
+	return stdout.String(), nil
+}
+
+// Batch feeds the commands to source-file on stdin. Unlike a \;-separated
+// command line, source-file keeps going after a failing command.
+func (execRunner) Batch(commands [][]string) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	cmd := exec.Command("tmux", append([]string{"-u"}, tmuxArgs("source-file", "-")...)...)
+	cmd.Stdin = strings.NewReader(batchScript(commands))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return &CommandError{Args: []string{"source-file", "-"}, Stderr: strings.TrimSpace(stderr.String()), Err: err}
+	}
+	return nil
+}
+
+func (execRunner) Exec(args ...string) error {
+	tmuxPath, err := exec.LookPath("tmux")
+	if err != nil {
+		return err
+	}
+
+	return syscall.Exec(tmuxPath, append([]string{"tmux"}, tmuxArgs(args...)...), os.Environ())
+}
+
+// tmuxOutput runs a tmux command and returns its trimmed stdout.
+func tmuxOutput(args ...string) (string, error) {
+	out, err := runner.Run(args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// execTmux replaces the current process with tmux.
+func execTmux(args ...string) error {
+	return runner.Exec(args...)
+}
+
+// runTmuxQuiet runs a tmux command without output, returning tmux's
+// error message on failure.
+func runTmuxQuiet(args ...string) error {
+	_, err := runner.Run(args...)
+	return err
+}
+
+// batch collects tmux commands to run in one tmux invocation.
+type batch [][]string
+
+func (b *batch) add(args ...string) {
+	*b = append(*b, args)
+}
+
+// runBatch runs commands in one tmux process. Failures are non-fatal, as with
+// runTmuxSilent, but are reported when P_DEBUG is set.
+func runBatch(b batch) {
+	if err := runner.Batch(b); err != nil && clierr.DebugEnabled() {
+		fmt.Fprintf(os.Stderr, "debug: tmux batch of %d commands reported errors:\n%v\n", len(b), err)
+	}
+}
+
+// batchScript renders commands in tmux's config syntax, one per line.
+func batchScript(commands [][]string) string {
+	var sb strings.Builder
+	for _, args := range commands {
+		for i, arg := range args {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(quoteArg(arg))
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// quoteArg single-quotes an argument for tmux's parser so that #, $, ~, ;
+// and braces are taken literally. Embedded single quotes are spliced in
+// from a double-quoted string.
+func quoteArg(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// runTmuxSilent runs a tmux command silently, ignoring errors.
+// Used for configuration where failure is non-fatal.
+func runTmuxSilent(args ...string) {
+	_, _ = runner.Run(args...)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,298 @@
+package tmux
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeRunner records every tmux invocation. respond, when set, decides the
+// result of Run; otherwise every command succeeds with no output.
+type fakeRunner struct {
+	calls   [][]string
+	batches [][][]string
+	execs   [][]string
+	respond func(args []string) (string, error)
+}
+
+func (f *fakeRunner) Run(args ...string) (string, error) {
+	f.calls = append(f.calls, append([]string(nil), args...))
+	if f.respond != nil {
+		return f.respond(args)
+	}
+	return "", nil
+}
+
+// Batch records the batch and runs each command through respond, joining
+// failures the way source-file does.
+func (f *fakeRunner) Batch(commands [][]string) error {
+	f.batches = append(f.batches, commands)
+	if f.respond == nil {
+		return nil
+	}
+	var failures []string
+	for _, args := range commands {
+		if _, err := f.respond(args); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return tmuxFailure(strings.Join(failures, "\n"))
+	}
+	return nil
+}
+
+func (f *fakeRunner) Exec(args ...string) error {
+	f.execs = append(f.execs, append([]string(nil), args...))
+	return nil
+}
+
+// commands returns the tmux command name of each recorded call.
+func (f *fakeRunner) commands() []string {
+	names := make([]string, len(f.calls))
+	for i, c := range f.calls {
+		names[i] = c[0]
+	}
+	return names
+}
+
+func useFakeRunner(t *testing.T, respond func(args []string) (string, error)) *fakeRunner {
+	t.Helper()
+	fake := &fakeRunner{respond: respond}
+	t.Cleanup(SetRunner(fake))
+	return fake
+}
+
+func tmuxFailure(stderr string) error {
+	return &CommandError{Stderr: stderr, Err: errors.New("exit status 1")}
+}
+
+// testLayout is the default home,cmd layout.
+var testLayout = Layout{Windows: []WindowSpec{{Name: "home", Panes: []PaneSpec{{}}}, {Name: "cmd", Panes: []PaneSpec{{}}}}}
+
+func TestCreateSessionRunsExpectedCommands(t *testing.T) {
+	fake := useFakeRunner(t, nil)
+
+	action, err := CreateSession("api", "/work/api", testLayout)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if action != LaunchActionCreate {
+		t.Fatalf("action mismatch: got %q", action)
+	}
+
+	if want := [][]string{{"new-session", "-d", "-s", "api", "-c", "/work/api"}}; !reflect.DeepEqual(fake.calls, want) {
+		t.Fatalf("calls: got %q want %q", fake.calls, want)
+	}
+	if len(fake.batches) != 1 {
+		t.Fatalf("expected configuration in a single batch, got %d", len(fake.batches))
+	}
+	cmds := fake.batches[0]
+	n := len(cmds)
+	wantTail := [][]string{
+		{"rename-window", "-tapi:^", "home"},
+		{"new-window", "-tapi:", "-c", "/work/api", "-n", "cmd"},
+		{"select-window", "-tapi:^"},
+	}
+	if !reflect.DeepEqual(cmds[n-len(wantTail):], wantTail) {
+		t.Fatalf("window commands: got %q want %q", cmds[n-len(wantTail):], wantTail)
+	}
+	for _, c := range cmds[:n-len(wantTail)] {
+		switch c[0] {
+		case "set-option", "set-hook", "bind-key":
+		default:
+			t.Fatalf("unexpected configure command: %q", c)
+		}
+	}
+}
+
+func TestCreateSessionReusesDuplicateInSameDirectory(t *testing.T) {
+	dir := t.TempDir()
+	fake := useFakeRunner(t, func(args []string) (string, error) {
+		switch args[0] {
+		case "new-session":
+			return "", tmuxFailure("duplicate session: api")
+		case "display-message":
+			return dir + "\n", nil
+		}
+		return "", nil
+	})
+
+	action, err := CreateSession("api", dir, testLayout)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if action != LaunchActionAttachExisting {
+		t.Fatalf("action mismatch: got %q", action)
+	}
+	if want := []string{"new-session", "display-message"}; !reflect.DeepEqual(fake.commands(), want) {
+		t.Fatalf("expected no reconfiguration, got %q", fake.commands())
+	}
+}
+
+func TestCreateSessionRejectsDuplicateInOtherDirectory(t *testing.T) {
+	useFakeRunner(t, func(args []string) (string, error) {
+		switch args[0] {
+		case "new-session":
+			return "", tmuxFailure("duplicate session: api")
+		case "display-message":
+			return "/somewhere/else\n", nil
+		}
+		return "", nil
+	})
+
+	_, err := CreateSession("api", t.TempDir(), testLayout)
+	var dupErr *duplicateSessionError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("expected duplicate session error, got %v", err)
+	}
+}
+
+func TestCreateSessionReportsTmuxFailure(t *testing.T) {
+	useFakeRunner(t, func(args []string) (string, error) {
+		return "", tmuxFailure("create window failed: fork failed")
+	})
+
+	_, err := CreateSession("api", "/work/api", testLayout)
+	if err == nil || !strings.Contains(err.Error(), "fork failed") {
+		t.Fatalf("expected tmux error to surface, got %v", err)
+	}
+}
+
+func TestBatchScriptQuotesArguments(t *testing.T) {
+	script := batchScript([][]string{
+		{"set-option", "-t", "api", "status-left", "it's #[fg=colour108]#S ; $HOME"},
+		{"select-window", "-tapi:0"},
+	})
+	want := `'set-option' '-t' 'api' 'status-left' 'it'"'"'s #[fg=colour108]#S ; $HOME'` + "\n" +
+		`'select-window' '-tapi:0'` + "\n"
+	if script != want {
+		t.Fatalf("script:\n got %s\nwant %s", script, want)
+	}
+}
+
+func TestListSessionsNoServer(t *testing.T) {
+	useFakeRunner(t, func(args []string) (string, error) {
+		return "", tmuxFailure("no server running on /tmp/tmux-1000/default")
+	})
+
+	_, err := ListSessions()
+	if !IsNoServerError(err) {
+		t.Fatalf("expected no-server error, got %v", err)
+	}
+}
+
+func TestListSessionsParsesAttachedAndCurrent(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	useFakeRunner(t, func(args []string) (string, error) {
+		switch args[0] {
+		case "list-sessions":
+			return "api\t1\t\nweb\t0\tcolour173\n", nil
+		case "display-message":
+			return "web\n", nil
+		}
+		return "", nil
+	})
+
+	sessions, err := ListSessions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Session{{Name: "api", Attached: true}, {Name: "web", Current: true, Accent: "colour173"}}
+	if !reflect.DeepEqual(sessions, want) {
+		t.Fatalf("sessions: got %+v want %+v", sessions, want)
+	}
+}
+
+func TestAttachToSessionSwitchesClientInsideTmux(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	fake := useFakeRunner(t, func(args []string) (string, error) {
+		if args[0] == "display-message" {
+			return "home\n", nil
+		}
+		return "", nil
+	})
+
+	if err := AttachToSession(Target{Session: "api", Window: "2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := [][]string{{"switch-client", "-t", "api"}}; !reflect.DeepEqual(fake.execs, want) {
+		t.Fatalf("exec: got %q want %q", fake.execs, want)
+	}
+	found := false
+	for _, c := range fake.calls {
+		if reflect.DeepEqual(c, []string{"select-window", "-t", "api:2"}) {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected select-window call, got %q", fake.calls)
+	}
+}
+
+func TestAttachToSessionSkipsSwitchToCurrentSession(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	fake := useFakeRunner(t, func(args []string) (string, error) {
+		if args[0] == "display-message" {
+			return "api\n", nil
+		}
+		return "", nil
+	})
+
+	if err := AttachToSession(Target{Session: "api"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.execs) != 0 {
+		t.Fatalf("expected no switch-client, got %q", fake.execs)
+	}
+}
+
+func TestAttachToSessionRefusesCrossServerSwitch(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	fake := useFakeRunner(t, nil)
+
+	err := AttachToSession(Target{Session: "api", Server: Server{Socket: "/tmp/pair.sock"}})
+	if err == nil || !strings.Contains(err.Error(), "/tmp/pair.sock") {
+		t.Fatalf("expected cross-server error, got %v", err)
+	}
+	if len(fake.execs) != 0 {
+		t.Fatalf("expected no exec, got %q", fake.execs)
+	}
+}
+
+func TestConfigureSessionSkipsCurrentVersion(t *testing.T) {
+	applied := configVersion("api", "")
+	for _, tc := range []struct {
+		name    string
+		stamp   string
+		force   bool
+		batches int
+	}{
+		{name: "unstamped", stamp: "", batches: 1},
+		{name: "current", stamp: applied, batches: 0},
+		{name: "outdated", stamp: "0123456789ab", batches: 1},
+		{name: "forced", stamp: applied, force: true, batches: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := useFakeRunner(t, func(args []string) (string, error) {
+				if args[0] == "display-message" {
+					return "/work/api\t\t" + tc.stamp + "\n", nil
+				}
+				return "", nil
+			})
+			t.Cleanup(SetForceConfigure(tc.force))
+
+			configureSession("api")
+			if len(fake.batches) != tc.batches {
+				t.Fatalf("batches: got %d want %d", len(fake.batches), tc.batches)
+			}
+			if tc.batches == 1 {
+				last := fake.batches[0][len(fake.batches[0])-1]
+				if want := []string{"set-option", "-tapi", configVersionOption, applied}; !reflect.DeepEqual(last, want) {
+					t.Fatalf("stamp: got %q want %q", last, want)
+				}
+			}
+		})
+	}
+}
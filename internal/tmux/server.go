@@ -0,0 +1,133 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Server selects the tmux server p talks to. The zero value is tmux's
+// default server (or, inside tmux, the server of the current client).
+type Server struct {
+	// Name is a socket name, passed as -L.
+	Name string
+	// Socket is a socket path, passed as -S. It takes precedence over Name.
+	Socket string
+}
+
+// ParseServer reads a socket name or path. Values containing a slash are
+// socket paths (-S); anything else is a socket name (-L).
+func ParseServer(s string) (Server, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Server{}, fmt.Errorf("empty tmux socket")
+	}
+	if !strings.Contains(s, "/") {
+		return Server{Name: s}, nil
+	}
+	abs, err := filepath.Abs(s)
+	if err != nil {
+		return Server{}, err
+	}
+	return Server{Socket: abs}, nil
+}
+
+// String labels the server for display.
+func (s Server) String() string {
+	switch {
+	case s.Socket != "":
+		return s.Socket
+	case s.Name != "":
+		return s.Name
+	default:
+		return "default"
+	}
+}
+
+func (s Server) flags() []string {
+	switch {
+	case s.Socket != "":
+		return []string{"-S", s.Socket}
+	case s.Name != "":
+		return []string{"-L", s.Name}
+	default:
+		return nil
+	}
+}
+
+// socketPath mirrors how tmux locates a server's socket. Without -L or -S,
+// tmux uses the socket of the client it runs in.
+func (s Server) socketPath() string {
+	if s.Socket != "" {
+		return s.Socket
+	}
+	if s.Name == "" {
+		if socket := clientSocket(); socket != "" {
+			return socket
+		}
+	}
+	name := s.Name
+	if name == "" {
+		name = "default"
+	}
+	dir := os.Getenv("TMUX_TMPDIR")
+	if dir == "" {
+		dir = "/tmp"
+	}
+	return filepath.Join(dir, fmt.Sprintf("tmux-%d", os.Getuid()), name)
+}
+
+// clientSocket returns the socket of the tmux client p runs in, if any.
+func clientSocket() string {
+	socket, _, _ := strings.Cut(os.Getenv("TMUX"), ",")
+	return socket
+}
+
+// insideServer reports whether p runs inside a client of s.
+func insideServer(s Server) bool {
+	socket := clientSocket()
+	return socket != "" && socket == s.socketPath()
+}
+
+var server Server
+
+// SetServer points the default runner at another tmux server and returns a
+// function that restores the previous one.
+func SetServer(s Server) (restore func()) {
+	prev := server
+	server = s
+	return func() { server = prev }
+}
+
+// CurrentServer returns the selected server.
+func CurrentServer() Server {
+	return server
+}
+
+// WithServer runs fn against s, restoring the previous server afterwards.
+func WithServer(s Server, fn func() error) error {
+	restore := SetServer(s)
+	defer restore()
+	return fn()
+}
+
+// ListSessionsOn lists sessions across several servers, skipping servers
+// that are not running. Each session records the server it came from.
+func ListSessionsOn(servers []Server) ([]Session, error) {
+	var all []Session
+	for _, s := range servers {
+		err := WithServer(s, func() error {
+			sessions, err := ListSessions()
+			if err != nil {
+				return err
+			}
+			all = append(all, sessions...)
+			return nil
+		})
+		if err != nil && !IsNoServerError(err) {
+			return nil, fmt.Errorf("%s: %w", s, err)
+		}
+	}
+	return all, nil
+}
@@ -1,74 +1,154 @@
 package tmux
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
 // Session represents a tmux session.
 type Session struct {
 	Name string
+	// Attached is true when at least one client is attached to the session.
+	Attached bool
+	// Current is true when the session belongs to the client p runs in.
+	Current bool
+	// Server is the tmux server the session lives on.
+	Server Server
+	// Accent is the session's accent colour in tmux syntax, or empty when it
+	// uses the theme's.
+	Accent string
 }
 
 // ListSessions returns all existing tmux sessions.
 // Returns empty slice if no server is running.
 func ListSessions() ([]Session, error) {
-	cmd := exec.Command("tmux", "-f", "/dev/null", "list-sessions", "-F", "#{session_name}")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
+	output, err := tmuxOutput("list-sessions", "-F", "#{session_name}\t#{session_attached}\t#{"+accentColourOption+"}")
 	if err != nil {
-		// Check if this is a "no server running" error
-		if IsNoServerError(err) {
-			return nil, err
-		}
 		return nil, err
 	}
-
-	output := strings.TrimSpace(stdout.String())
 	if output == "" {
 		return nil, nil
 	}
 
+	current, _ := CurrentSession()
+	self := ""
+	if c, ok := runner.(interface{ attachedSession() string }); ok {
+		self = c.attachedSession()
+	}
+
 	lines := strings.Split(output, "\n")
 	sessions := make([]Session, 0, len(lines))
 	for _, line := range lines {
-		name := strings.TrimSpace(line)
-		if name != "" {
-			sessions = append(sessions, Session{Name: name})
+		name, rest, _ := strings.Cut(line, "\t")
+		attached, accent, _ := strings.Cut(rest, "\t")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		clients, _ := strconv.Atoi(strings.TrimSpace(attached))
+		// A control-mode runner is itself a client of one session.
+		if name == self {
+			clients--
 		}
+		sessions = append(sessions, Session{
+			Name:     name,
+			Attached: clients > 0,
+			Current:  name == current,
+			Server:   server,
+			Accent:   strings.TrimSpace(accent),
+		})
 	}
 
 	return sessions, nil
 }
 
+// CurrentSession returns the name of the session p is running in.
+// Returns an empty name when p is not running inside a client of the
+// selected server.
+func CurrentSession() (string, error) {
+	if !insideServer(server) {
+		return "", nil
+	}
+	args := []string{"display-message", "-p"}
+	// Without a target, display-message reports the calling client's session,
+	// which is not ours when commands go over a control-mode connection.
+	if pane := os.Getenv("TMUX_PANE"); pane != "" {
+		args = append(args, "-t", pane)
+	}
+	name, err := tmuxOutput(append(args, "#S")...)
+	if err != nil {
+		return "", fmt.Errorf("failed to read current session: %w", err)
+	}
+	return name, nil
+}
+
 // GetSessionPath returns the directory the session was created in.
 func GetSessionPath(sessionName string) (string, error) {
-	cmd := exec.Command("tmux", "-f", "/dev/null", "display-message", "-p", "-t", sessionName, "#{session_path}")
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to read session path: %s", strings.TrimSpace(stderr.String()))
+	path, err := tmuxOutput("display-message", "-p", "-t", sessionTarget(sessionName), "#{session_path}")
+	if err != nil {
+		return "", fmt.Errorf("failed to read session path: %w", err)
 	}
-	path := strings.TrimSpace(stdout.String())
 	if path == "" {
 		return "", fmt.Errorf("session path not available")
 	}
 	return path, nil
 }
 
+// sessionTarget is a target for exactly the session called name. A plain
+// name given to a command taking a pane target can resolve to a window of
+// another session with that name.
+func sessionTarget(name string) string {
+	return "=" + name + ":"
+}
+
+// SessionsInDirectory returns the names of the sessions rooted in dir.
+// It returns none when no server is running.
+func SessionsInDirectory(dir string) ([]string, error) {
+	sessions, err := ListSessions()
+	if err != nil {
+		if IsNoServerError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, s := range sessions {
+		matching, err := sessionMatchesDirectory(s.Name, dir)
+		if err != nil {
+			return nil, err
+		}
+		if matching {
+			names = append(names, s.Name)
+		}
+	}
+	return names, nil
+}
+
+// KillSession ends a session and every process running in it.
+func KillSession(sessionName string) error {
+	if _, err := runner.Run("kill-session", "-t", "="+sessionName); err != nil {
+		return fmt.Errorf("failed to kill session %q: %w", sessionName, err)
+	}
+	return nil
+}
+
 // IsNoServerError checks if the error indicates no tmux server is running.
 func IsNoServerError(err error) bool {
 	if err == nil {
 		return false
 	}
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Stderr != "" {
+		return strings.Contains(cmdErr.Stderr, "no server running") ||
+			strings.Contains(cmdErr.Stderr, "error connecting to")
+	}
 	// tmux returns exit code 1 when no server is running
-	if exitErr, ok := err.(*exec.ExitError); ok {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
 		return exitErr.ExitCode() == 1
 	}
 	return false
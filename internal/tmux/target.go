@@ -0,0 +1,133 @@
+package tmux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Target identifies a session, optionally narrowed to a window and a pane.
+// Window may be an index or a name; Pane is a pane index.
+type Target struct {
+	Session string
+	Window  string
+	Pane    string
+	// Server is the server the session lives on. The zero value means the
+	// currently selected server.
+	Server Server
+}
+
+// String renders the target in tmux's session:window.pane syntax.
+// The server is not part of the string.
+func (t Target) String() string {
+	s := t.Session
+	if t.Window != "" {
+		s += ":" + t.Window
+		if t.Pane != "" {
+			s += "." + t.Pane
+		}
+	}
+	return s
+}
+
+// ParseTarget parses session[:window[.pane]]. The window may be an index or
+// a name; a trailing .N is read as a pane index so window names containing
+// dots still work.
+func ParseTarget(s string) (Target, error) {
+	session, rest, hasWindow := strings.Cut(s, ":")
+	if session == "" {
+		return Target{}, fmt.Errorf("invalid target %q: missing session name", s)
+	}
+	t := Target{Session: session}
+	if !hasWindow {
+		return t, nil
+	}
+	if rest == "" {
+		return Target{}, fmt.Errorf("invalid target %q: missing window after ':'", s)
+	}
+	t.Window = rest
+	if i := strings.LastIndex(rest, "."); i >= 0 {
+		if _, err := strconv.Atoi(rest[i+1:]); err == nil {
+			t.Window, t.Pane = rest[:i], rest[i+1:]
+		}
+	}
+	if t.Window == "" {
+		return Target{}, fmt.Errorf("invalid target %q: missing window before '.'", s)
+	}
+	return t, nil
+}
+
+// ResolveTarget checks that each part of the target exists on the selected
+// server and returns it with the window normalized to its index. Errors name
+// the part that did not resolve.
+func ResolveTarget(t Target) (Target, error) {
+	sessions, err := ListSessions()
+	if err != nil && !IsNoServerError(err) {
+		return Target{}, err
+	}
+	found := false
+	for _, s := range sessions {
+		if s.Name == t.Session {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return Target{}, fmt.Errorf("session %q not found", t.Session)
+	}
+	if t.Window == "" {
+		return t, nil
+	}
+
+	windows, err := ListWindows(t.Session)
+	if err != nil {
+		return Target{}, err
+	}
+	window, err := matchWindow(windows, t.Session, t.Window)
+	if err != nil {
+		return Target{}, err
+	}
+	resolved := window.Target()
+	resolved.Server = t.Server
+	if t.Pane == "" {
+		return resolved, nil
+	}
+
+	panes, err := ListPanes(t.Session, window.Index)
+	if err != nil {
+		return Target{}, err
+	}
+	for _, p := range panes {
+		if strconv.Itoa(p.Index) == t.Pane {
+			resolved = p.Target()
+			resolved.Server = t.Server
+			return resolved, nil
+		}
+	}
+	return Target{}, fmt.Errorf("pane %s not found in window %q of session %q", t.Pane, t.Window, t.Session)
+}
+
+// matchWindow finds a window by index, falling back to an exact name match.
+func matchWindow(windows []Window, session, want string) (Window, error) {
+	if index, err := strconv.Atoi(want); err == nil {
+		for _, w := range windows {
+			if w.Index == index {
+				return w, nil
+			}
+		}
+	}
+	var matches []Window
+	for _, w := range windows {
+		if w.Name == want {
+			matches = append(matches, w)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Window{}, fmt.Errorf("window %q not found in session %q", want, session)
+	case 1:
+		return matches[0], nil
+	default:
+		return Window{}, fmt.Errorf("window name %q is ambiguous in session %q; use its index", want, session)
+	}
+}
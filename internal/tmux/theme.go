@@ -0,0 +1,175 @@
+package tmux
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+// theme is a palette for the status bar and panes, in tmux style syntax.
+type theme struct {
+	status   string // status-style
+	accent   string // status-left and status-right segments
+	inactive string // window list entries
+	current  string // the current window's entry
+	pane     string // window-style and window-active-style
+}
+
+// themes are the presets selectable with P_THEME.
+var themes = map[string]theme{
+	// Savvy AI aesthetic (ADR-005): dark gray bar (colour235 ≈ #4e4e4e),
+	// light gray text (colour240), sage green accent (colour108), black panes.
+	"default": {
+		status:   "bg=colour235,fg=colour240",
+		accent:   "fg=colour108",
+		inactive: "fg=colour240",
+		current:  "fg=white,bold",
+		pane:     "bg=colour16",
+	},
+	"light": {
+		status:   "bg=colour254,fg=colour244",
+		accent:   "fg=colour25",
+		inactive: "fg=colour244",
+		current:  "fg=colour232,bold",
+		pane:     "bg=colour231",
+	},
+	"high-contrast": {
+		status:   "bg=black,fg=white",
+		accent:   "fg=brightyellow,bold",
+		inactive: "fg=white",
+		current:  "fg=black,bg=brightyellow,bold",
+		pane:     "bg=black",
+	},
+	// mono relies on attributes only, for terminals or users without colour.
+	"mono": {
+		status:   "default",
+		accent:   "bold",
+		inactive: "dim",
+		current:  "reverse",
+		pane:     "default",
+	},
+}
+
+const defaultThemeName = "default"
+
+// statusSegments maps segment names usable in P_STATUS_LEFT and
+// P_STATUS_RIGHT to tmux formats. cmd:<command> runs a shell command.
+var statusSegments = map[string]string{
+	"session": "[#S]",
+	"branch":  "#(git -C #{pane_current_path} rev-parse --abbrev-ref HEAD 2>/dev/null)",
+	"git":     "#(git -C #{pane_current_path} rev-parse --abbrev-ref HEAD 2>/dev/null)#(git -C #{pane_current_path} status --porcelain 2>/dev/null | grep -q . && echo '*')",
+	"windows": "#{session_windows}w",
+	"clock":   "%H:%M",
+	"host":    "#h",
+}
+
+const cmdSegmentPrefix = "cmd:"
+
+// statusConfig is the status bar selected through the environment. left
+// and right are the segment formats, without styling.
+type statusConfig struct {
+	theme  theme
+	left   string
+	right  string
+	length string
+}
+
+// defaultStatusLength fits the default segments; custom segments get room
+// for a few more.
+const (
+	defaultStatusLength = "40"
+	customStatusLength  = "80"
+)
+
+// loadStatusConfig reads P_THEME, P_STATUS_LEFT and P_STATUS_RIGHT.
+func loadStatusConfig() (statusConfig, error) {
+	return newStatusConfig(os.Getenv("P_THEME"), os.Getenv("P_STATUS_LEFT"), os.Getenv("P_STATUS_RIGHT"))
+}
+
+// newStatusConfig resolves a theme name and segment lists; empty values
+// select the defaults.
+func newStatusConfig(name, leftEnv, rightEnv string) (statusConfig, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = defaultThemeName
+	}
+	t, ok := themes[name]
+	if !ok {
+		return statusConfig{}, fmt.Errorf("unknown P_THEME %q (available: %s)", name, strings.Join(themeNames(), ", "))
+	}
+	left, err := renderSegments("P_STATUS_LEFT", leftEnv, "session")
+	if err != nil {
+		return statusConfig{}, err
+	}
+	right, err := renderSegments("P_STATUS_RIGHT", rightEnv, "branch")
+	if err != nil {
+		return statusConfig{}, err
+	}
+	length := defaultStatusLength
+	if strings.TrimSpace(leftEnv+rightEnv) != "" {
+		length = customStatusLength
+	}
+	return statusConfig{theme: t, left: left, right: right, length: length}, nil
+}
+
+// accentStyle is the style of the status segments: the session's accent
+// colour when it has one, otherwise the theme's.
+func (c statusConfig) accentStyle(accent string) string {
+	if accent == "" {
+		return c.theme.accent
+	}
+	return "fg=" + accent
+}
+
+// CheckStatusConfig reports an invalid theme or status segment, so it can
+// be shown up front rather than silently falling back to the defaults.
+func CheckStatusConfig() error {
+	_, err := loadStatusConfig()
+	return err
+}
+
+// currentStatusConfig is loadStatusConfig with the defaults on error.
+func currentStatusConfig() statusConfig {
+	c, err := loadStatusConfig()
+	if err != nil {
+		c, _ = newStatusConfig("", "", "")
+	}
+	return c
+}
+
+// renderSegments turns a comma-separated segment list into a tmux format.
+func renderSegments(variable, value, fallback string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		value = fallback
+	}
+	var parts []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if cmd, ok := strings.CutPrefix(name, cmdSegmentPrefix); ok {
+			if strings.TrimSpace(cmd) == "" {
+				return "", fmt.Errorf("%s: %s needs a command", variable, cmdSegmentPrefix)
+			}
+			parts = append(parts, "#("+cmd+")")
+			continue
+		}
+		format, ok := statusSegments[name]
+		if !ok {
+			return "", fmt.Errorf("%s: unknown segment %q (available: %s, %s<command>)", variable, name, strings.Join(segmentNames(), ", "), cmdSegmentPrefix)
+		}
+		parts = append(parts, format)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func themeNames() []string {
+	return slices.Sorted(maps.Keys(themes))
+}
+
+func segmentNames() []string {
+	return slices.Sorted(maps.Keys(statusSegments))
+}
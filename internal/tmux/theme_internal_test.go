@@ -0,0 +1,53 @@
+package tmux
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewStatusConfigDefaults(t *testing.T) {
+	c, err := newStatusConfig("", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.left != "[#S]" {
+		t.Fatalf("left: got %q", c.left)
+	}
+	if c.right != "#(git -C #{pane_current_path} rev-parse --abbrev-ref HEAD 2>/dev/null)" {
+		t.Fatalf("right: got %q", c.right)
+	}
+	if c.length != defaultStatusLength || c.theme != themes["default"] {
+		t.Fatalf("unexpected config: %+v", c)
+	}
+}
+
+func TestNewStatusConfigSegments(t *testing.T) {
+	c, err := newStatusConfig("mono", "session, windows", "git,clock,host,cmd:uptime -p")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.left != "[#S] #{session_windows}w" {
+		t.Fatalf("left: got %q", c.left)
+	}
+	for _, want := range []string{"status --porcelain", "%H:%M #h #(uptime -p)"} {
+		if !strings.Contains(c.right, want) {
+			t.Fatalf("right %q missing %q", c.right, want)
+		}
+	}
+	if c.length != customStatusLength {
+		t.Fatalf("length: got %q", c.length)
+	}
+}
+
+func TestNewStatusConfigErrors(t *testing.T) {
+	for _, tc := range []struct{ theme, left, right, want string }{
+		{theme: "solarized", want: "unknown P_THEME"},
+		{left: "session,weather", want: `P_STATUS_LEFT: unknown segment "weather"`},
+		{right: "cmd:", want: "P_STATUS_RIGHT: cmd: needs a command"},
+	} {
+		_, err := newStatusConfig(tc.theme, tc.left, tc.right)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%+v: expected %q, got %v", tc, tc.want, err)
+		}
+	}
+}
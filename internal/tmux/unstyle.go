@@ -0,0 +1,203 @@
+package tmux
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// savedOptionsOption is the session user option holding, as JSON, the
+// session's own values for the options p overrides. It is written the first
+// time p configures a session and read back by UnstyleSession.
+const savedOptionsOption = "@p_saved_options"
+
+// savedOptions records options and after-new-window hook entries that were
+// set on a session or its windows before p styled it. Options that were
+// inherited are not recorded; they are restored by unsetting p's value.
+type savedOptions struct {
+	Session map[string]string            `json:"session,omitempty"`
+	Windows map[string]map[string]string `json:"windows,omitempty"`
+	Hooks   map[string]string            `json:"hooks,omitempty"`
+}
+
+// saveOptionsCommands returns the command recording the session's current
+// values of p's options, or nothing when they were already recorded.
+func saveOptionsCommands(sessionName string, windows []Window) batch {
+	set, err := localOptions("-t", sessionName)
+	if err != nil {
+		return nil
+	}
+	if _, ok := set[savedOptionsOption]; ok {
+		return nil
+	}
+
+	var saved savedOptions
+	saved.Session = overriddenValues(set, sessionStyle(currentStatusConfig(), ""), "-t", sessionName)
+	saved.Hooks = hookEntries("-t", sessionName)
+	for _, w := range windows {
+		target := w.Target().String()
+		set, err := localOptions("-w", "-t", target)
+		if err != nil {
+			continue
+		}
+		if values := overriddenValues(set, windowStyle(currentStatusConfig()), "-w", "-t", target); len(values) > 0 {
+			if saved.Windows == nil {
+				saved.Windows = map[string]map[string]string{}
+			}
+			saved.Windows[strconv.Itoa(w.Index)] = values
+		}
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return nil
+	}
+	var b batch
+	b.add("set-option", "-t"+sessionName, savedOptionsOption, string(data))
+	return b
+}
+
+// inheritHookCommands copies the global after-new-window entries onto a
+// session that has none of its own, so they keep running once p's entry
+// is added. Entries the user later adds globally are not picked up until
+// the session is unstyled.
+func inheritHookCommands(sessionName string) batch {
+	if len(hookEntries("-t", sessionName)) > 0 {
+		return nil
+	}
+	global := hookEntries("-g")
+	var b batch
+	for _, name := range slices.Sorted(maps.Keys(global)) {
+		b.add("set-hook", "-t"+sessionName, name, global[name])
+	}
+	return b
+}
+
+// hookEntries returns the after-new-window entries set in a scope, other
+// than p's own, keyed by indexed name: after-new-window[0].
+func hookEntries(scope ...string) map[string]string {
+	out, err := tmuxOutput(append(append([]string{"show-hooks"}, scope...), "after-new-window")...)
+	if err != nil {
+		return nil
+	}
+	entries := map[string]string{}
+	for _, line := range splitLines(out) {
+		name, command, ok := strings.Cut(line, " ")
+		if !ok || name == newWindowHook || optionBase(name) != "after-new-window" {
+			continue
+		}
+		entries[name] = command
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return entries
+}
+
+// localOptions returns the names of options set directly on a session or
+// window, rather than inherited.
+func localOptions(scope ...string) (map[string]bool, error) {
+	out, err := tmuxOutput(append([]string{"show-options"}, scope...)...)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, line := range splitLines(out) {
+		if name, _, _ := strings.Cut(line, " "); name != "" {
+			names[name] = true
+		}
+	}
+	return names, nil
+}
+
+// overriddenValues reads the values of locally set options that p overrides.
+// Array options are recorded per index.
+func overriddenValues(set map[string]bool, overrides []option, scope ...string) map[string]string {
+	values := map[string]string{}
+	for name := range set {
+		if !overridesOption(overrides, name) {
+			continue
+		}
+		args := append(append([]string{"show-options", "-qv"}, scope...), name)
+		value, err := runner.Run(args...)
+		if err != nil {
+			continue
+		}
+		values[name] = strings.TrimSuffix(value, "\n")
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+func overridesOption(overrides []option, name string) bool {
+	for _, o := range overrides {
+		if optionBase(o.name) == optionBase(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// optionBase strips an array index: status-format[0] becomes status-format.
+func optionBase(name string) string {
+	base, _, _ := strings.Cut(name, "[")
+	return base
+}
+
+// UnstyleSession removes p's configuration from a session, restoring the
+// options and hook entries it had before p first styled it.
+func UnstyleSession(sessionName string) error {
+	raw, err := tmuxOutput("show-options", "-qv", "-t", sessionName, savedOptionsOption)
+	if err != nil {
+		return fmt.Errorf("failed to read saved options: %w", err)
+	}
+	var saved savedOptions
+	if raw != "" {
+		if err := json.Unmarshal([]byte(raw), &saved); err != nil {
+			return fmt.Errorf("invalid %s on session %q: %w", savedOptionsOption, sessionName, err)
+		}
+	}
+	windows, err := ListWindows(sessionName)
+	if err != nil {
+		return err
+	}
+	if err := runner.Batch(unstyleCommands(sessionName, windows, saved)); err != nil {
+		return fmt.Errorf("failed to restore session options: %w", err)
+	}
+	return nil
+}
+
+func unstyleCommands(sessionName string, windows []Window, saved savedOptions) batch {
+	var b batch
+	target := "-t" + sessionName
+	b.add("set-hook", "-u", target, "after-new-window")
+	for _, o := range sessionStyle(currentStatusConfig(), "") {
+		b.add("set-option", "-u", target, optionBase(o.name))
+	}
+	for _, w := range windows {
+		for _, o := range windowStyle(currentStatusConfig()) {
+			b.add("set-option", "-w", "-u", "-t"+w.Target().String(), o.name)
+		}
+	}
+	b.add("set-option", "-u", target, configVersionOption)
+	b.add("set-option", "-u", target, accentColourOption)
+	b.add("set-option", "-u", target, savedOptionsOption)
+
+	for _, name := range slices.Sorted(maps.Keys(saved.Session)) {
+		b.add("set-option", target, name, saved.Session[name])
+	}
+	for _, w := range windows {
+		values := saved.Windows[strconv.Itoa(w.Index)]
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			b.add("set-option", "-w", "-t"+w.Target().String(), name, values[name])
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(saved.Hooks)) {
+		b.add("set-hook", target, name, saved.Hooks[name])
+	}
+	return b
+}
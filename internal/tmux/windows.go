@@ -0,0 +1,115 @@
+package tmux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Window represents a window inside a tmux session.
+type Window struct {
+	Session string
+	Index   int
+	Name    string
+	Active  bool
+	Panes   int
+	// Layout is the window's layout string, as select-layout accepts it.
+	Layout string
+}
+
+// Pane represents a pane inside a tmux window.
+type Pane struct {
+	Session string
+	Window  int
+	Index   int
+	Command string
+	Path    string
+	Active  bool
+}
+
+// Target returns the target of the window.
+func (w Window) Target() Target {
+	return Target{Session: w.Session, Window: strconv.Itoa(w.Index)}
+}
+
+// Target returns the target of the pane.
+func (p Pane) Target() Target {
+	return Target{Session: p.Session, Window: strconv.Itoa(p.Window), Pane: strconv.Itoa(p.Index)}
+}
+
+// ListWindows returns the windows of a session in index order.
+func ListWindows(sessionName string) ([]Window, error) {
+	out, err := tmuxOutput("list-windows", "-t", sessionName, "-F", "#{window_index}\t#{window_name}\t#{window_active}\t#{window_panes}\t#{window_layout}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list windows: %w", err)
+	}
+	var windows []Window
+	for _, line := range splitLines(out) {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 5 {
+			continue
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		panes, _ := strconv.Atoi(fields[3])
+		windows = append(windows, Window{
+			Session: sessionName,
+			Index:   index,
+			Name:    fields[1],
+			Active:  fields[2] == "1",
+			Panes:   panes,
+			Layout:  fields[4],
+		})
+	}
+	return windows, nil
+}
+
+// ListPanes returns the panes of a window in index order.
+func ListPanes(sessionName string, windowIndex int) ([]Pane, error) {
+	target := Target{Session: sessionName, Window: strconv.Itoa(windowIndex)}
+	out, err := tmuxOutput("list-panes", "-t", target.String(), "-F", "#{pane_index}\t#{pane_current_command}\t#{pane_current_path}\t#{pane_active}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list panes: %w", err)
+	}
+	var panes []Pane
+	for _, line := range splitLines(out) {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		index, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		panes = append(panes, Pane{
+			Session: sessionName,
+			Window:  windowIndex,
+			Index:   index,
+			Command: fields[1],
+			Path:    fields[2],
+			Active:  fields[3] == "1",
+		})
+	}
+	return panes, nil
+}
+
+// CapturePane returns a pane's scrollback and visible content, with its
+// colours, wrapped lines joined and trailing blank lines dropped.
+func CapturePane(target Target) (string, error) {
+	out, err := runner.Run("capture-pane", "-p", "-e", "-J", "-S", "-", "-t", target.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to capture pane: %w", err)
+	}
+	return strings.TrimRight(out, "\n") + "\n", nil
+}
+
+// ReplayFile prints a file in a pane's shell after clearing the screen,
+// such as a pane's saved scrollback.
+func ReplayFile(target Target, path string) error {
+	if _, err := runner.Run("send-keys", "-t", target.String(), "clear && cat -- "+shellQuote(path), "Enter"); err != nil {
+		return fmt.Errorf("failed to replay %s: %w", path, err)
+	}
+	return nil
+}
@@ -1,12 +1,60 @@
 package ui
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	ansiEnterAltScreen = "\033[?1049h"
 	ansiExitAltScreen  = "\033[?1049l"
 	ansiClearScreen    = "\033[H\033[J"
 
 	ansiInvert = "\033[7m"
+	ansiDim    = "\033[2m"
 	ansiReset  = "\033[0m"
 
 	crlf = "\r\n"
 )
+
+// ansiDefaultFg restores the default foreground without clearing other
+// attributes such as inversion.
+const ansiDefaultFg = "\033[39m"
+
+// ansiBasicColours maps tmux's named colours to their SGR codes.
+var ansiBasicColours = map[string]int{
+	"black": 30, "red": 31, "green": 32, "yellow": 33,
+	"blue": 34, "magenta": 35, "cyan": 36, "white": 37,
+}
+
+// ansiForeground converts a tmux colour (colourN, #rrggbb or a name such as
+// brightred) to an SGR foreground sequence. It returns "" for colours it
+// does not understand.
+func ansiForeground(colour string) string {
+	for _, prefix := range []string{"colour", "color"} {
+		if n, ok := strings.CutPrefix(colour, prefix); ok {
+			if v, err := strconv.Atoi(n); err == nil && v >= 0 && v <= 255 {
+				return fmt.Sprintf("\033[38;5;%dm", v)
+			}
+			return ""
+		}
+	}
+	if hex, ok := strings.CutPrefix(colour, "#"); ok && len(hex) == 6 {
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("\033[38;2;%d;%d;%dm", v>>16, v>>8&0xff, v&0xff)
+	}
+	if name, ok := strings.CutPrefix(colour, "bright"); ok {
+		if code, ok := ansiBasicColours[name]; ok {
+			return fmt.Sprintf("\033[%dm", code+60)
+		}
+		return ""
+	}
+	if code, ok := ansiBasicColours[colour]; ok {
+		return fmt.Sprintf("\033[%dm", code)
+	}
+	return ""
+}
@@ -0,0 +1,20 @@
+package ui
+
+import "testing"
+
+func TestAnsiForeground(t *testing.T) {
+	for colour, want := range map[string]string{
+		"colour108":  "\033[38;5;108m",
+		"color7":     "\033[38;5;7m",
+		"#ff8800":    "\033[38;2;255;136;0m",
+		"red":        "\033[31m",
+		"brightblue": "\033[94m",
+		"colour300":  "",
+		"default":    "",
+		"":           "",
+	} {
+		if got := ansiForeground(colour); got != want {
+			t.Fatalf("%q: got %q want %q", colour, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"os"
+	"time"
 
 	"golang.org/x/sys/unix"
 )
@@ -15,6 +16,11 @@ const (
 	keyBackspace
 	keyUp
 	keyDown
+	keyLeft
+	keyRight
+	// keyCtrlH is 0x08: Ctrl+H, but also what Backspace sends on some
+	// terminals.
+	keyCtrlH
 	keyRune
 )
 
@@ -33,10 +39,14 @@ const (
 	byteCtrlN = 14
 	byteCtrlK = 11
 	byteCtrlP = 16
+	byteCtrlH = 8
+	byteCtrlL = 12
 
 	byteArrowPrefix = 91
 	byteArrowUp     = 65
 	byteArrowDown   = 66
+	byteArrowRight  = 67
+	byteArrowLeft   = 68
 )
 
 func readKeyEvent() (keyEvent, error) {
@@ -53,6 +63,10 @@ func readKeyEvent() (keyEvent, error) {
 			return keyEvent{kind: keyUp}, nil
 		case byteArrowDown:
 			return keyEvent{kind: keyDown}, nil
+		case byteArrowRight:
+			return keyEvent{kind: keyRight}, nil
+		case byteArrowLeft:
+			return keyEvent{kind: keyLeft}, nil
 		}
 	}
 
@@ -77,6 +91,10 @@ func readKeyEvent() (keyEvent, error) {
 			return keyEvent{kind: keyDown}, nil
 		case byteCtrlK, byteCtrlP:
 			return keyEvent{kind: keyUp}, nil
+		case byteCtrlH:
+			return keyEvent{kind: keyCtrlH}, nil
+		case byteCtrlL:
+			return keyEvent{kind: keyRight}, nil
 		default:
 			if b[0] >= 32 && b[0] < 127 {
 				return keyEvent{kind: keyRune, r: rune(b[0])}, nil
@@ -89,7 +107,7 @@ func readKeyEvent() (keyEvent, error) {
 
 func tryReadEscapeSequence() (bool, keyEvent, error) {
 	fd := int(os.Stdin.Fd())
-	ready, err := hasPendingInput(fd)
+	ready, err := waitForInput(fd, 0)
 	if err != nil {
 		return false, keyEvent{}, err
 	}
@@ -114,17 +132,26 @@ func tryReadEscapeSequence() (bool, keyEvent, error) {
 			return true, keyEvent{kind: keyUp}, nil
 		case 'B':
 			return true, keyEvent{kind: keyDown}, nil
+		case 'C':
+			return true, keyEvent{kind: keyRight}, nil
+		case 'D':
+			return true, keyEvent{kind: keyLeft}, nil
 		}
 	}
 	return false, keyEvent{}, nil
 }
 
-func hasPendingInput(fd int) (bool, error) {
+// waitForInput reports whether fd becomes readable within timeout.
+// A zero timeout polls without blocking.
+func waitForInput(fd int, timeout time.Duration) (bool, error) {
 	var set unix.FdSet
 	fdSet(fd, &set)
-	var tv unix.Timeval
+	tv := unix.NsecToTimeval(timeout.Nanoseconds())
 	n, err := unix.Select(fd+1, &set, nil, nil, &tv)
 	if err != nil {
+		if err == unix.EINTR {
+			return false, nil
+		}
 		return false, err
 	}
 	return n > 0, nil
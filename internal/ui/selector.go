@@ -7,34 +7,216 @@ import (
 	"github.com/wilmoore/p/internal/tmux"
 )
 
+// sessionNode is a row of the session selector: a session, or one of its
+// windows or panes after drilling down.
+type sessionNode struct {
+	session tmux.Session
+	window  *tmux.Window
+	pane    *tmux.Pane
+}
+
+func (n sessionNode) target() tmux.Target {
+	var t tmux.Target
+	switch {
+	case n.pane != nil:
+		t = n.pane.Target()
+	case n.window != nil:
+		t = n.window.Target()
+	default:
+		t = tmux.Target{Session: n.session.Name}
+	}
+	t.Server = n.session.Server
+	return t
+}
+
 // ShowSelector displays an fzf-like session selector.
-// Supports both numeric selection and text filtering.
-func ShowSelector(sessions []tmux.Session) (*tmux.Session, error) {
+// Supports both numeric selection and text filtering. When refresh is
+// non-nil the list is re-polled while the selector is open, and at once
+// whenever changes delivers a value. Right/Ctrl+L drills
+// into a session's windows and a window's panes; the returned target is
+// whatever level Enter was pressed on.
+func ShowSelector(sessions []tmux.Session, refresh func() ([]tmux.Session, error), changes <-chan struct{}) (*tmux.Target, error) {
 	if len(sessions) == 0 {
 		return nil, fmt.Errorf("no sessions available")
 	}
 
-	adapter := selectorAdapter[tmux.Session]{
+	sections, err := loadSessionSections()
+	if err != nil {
+		return nil, err
+	}
+	var refreshNodes func() ([]sessionNode, error)
+	if refresh != nil {
+		refreshNodes = func() ([]sessionNode, error) {
+			next, err := refresh()
+			if err != nil {
+				return nil, err
+			}
+			return sessionNodes(sections.sort(next)), nil
+		}
+	}
+
+	// Label rows with their server once sessions come from more than one.
+	labelServers := spansServers(sessions)
+	var swatch func(sessionNode) string
+	if hasAccents(sessions) {
+		swatch = func(n sessionNode) string {
+			if n.window != nil {
+				return ""
+			}
+			return ansiForeground(n.session.Accent)
+		}
+	}
+
+	adapter := selectorAdapter[sessionNode]{
 		title:        uiTitleSessions,
 		emptyMessage: uiNoMatches,
-		renderRow: func(s tmux.Session, width int) string {
-			return truncateRight(s.Name, width)
+		renderRow: func(n sessionNode, width int) string {
+			if labelServers && n.window == nil {
+				return truncateRight(fmt.Sprintf(uiServerLabelFmt, n.session.Server)+renderSessionNode(n, width), width)
+			}
+			return renderSessionNode(n, width)
+		},
+		searchText: func(n sessionNode) string {
+			if labelServers && n.window == nil {
+				return n.session.Server.String() + " " + sessionNodeSearchText(n)
+			}
+			return sessionNodeSearchText(n)
 		},
-		searchText: func(s tmux.Session) string {
-			return s.Name
+		identity: func(n sessionNode) string {
+			return n.session.Server.String() + "/" + n.target().String()
 		},
-		directSelect: func(query string) (*tmux.Session, bool) {
+		section: func(n sessionNode) string {
+			if n.window != nil {
+				return ""
+			}
+			return sections.label(n.session)
+		},
+		skipDefault: func(n sessionNode) bool {
+			return n.window == nil && n.session.Current
+		},
+		refresh:  refreshNodes,
+		changed:  changes,
+		swatch:   swatch,
+		children: sessionNodeChildren,
+		directSelect: func(query string, nodes []sessionNode) (*sessionNode, bool) {
 			idx, err := strconv.Atoi(query)
 			if err != nil {
 				return nil, false
 			}
-			if idx < 1 || idx > len(sessions) {
+			if idx < 1 || idx > len(nodes) {
 				return nil, false
 			}
-			chosen := sessions[idx-1]
+			chosen := nodes[idx-1]
 			return &chosen, true
 		},
 	}
 
-	return runSelector(sessions, adapter)
+	choice, err := runSelector(sessionNodes(sections.sort(sessions)), adapter)
+	if err != nil || choice == nil {
+		return nil, err
+	}
+	target := choice.target()
+	return &target, nil
+}
+
+func spansServers(sessions []tmux.Session) bool {
+	for _, s := range sessions {
+		if s.Server != sessions[0].Server {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAccents reports whether any session has an accent colour to show.
+func hasAccents(sessions []tmux.Session) bool {
+	for _, s := range sessions {
+		if s.Accent != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func sessionNodes(sessions []tmux.Session) []sessionNode {
+	nodes := make([]sessionNode, len(sessions))
+	for i, s := range sessions {
+		nodes[i] = sessionNode{session: s}
+	}
+	return nodes
+}
+
+func sessionNodeChildren(n sessionNode) (string, []sessionNode, error) {
+	switch {
+	case n.pane != nil:
+		return "", nil, nil
+	case n.window != nil:
+		var panes []tmux.Pane
+		err := tmux.WithServer(n.session.Server, func() (err error) {
+			panes, err = tmux.ListPanes(n.session.Name, n.window.Index)
+			return err
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		nodes := make([]sessionNode, len(panes))
+		for i := range panes {
+			nodes[i] = sessionNode{session: n.session, window: n.window, pane: &panes[i]}
+		}
+		return fmt.Sprintf(uiTitlePanesFmt, n.window.Target()), nodes, nil
+	default:
+		var windows []tmux.Window
+		err := tmux.WithServer(n.session.Server, func() (err error) {
+			windows, err = tmux.ListWindows(n.session.Name)
+			return err
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		nodes := make([]sessionNode, len(windows))
+		for i := range windows {
+			nodes[i] = sessionNode{session: n.session, window: &windows[i]}
+		}
+		return fmt.Sprintf(uiTitleWindowsFmt, n.session.Name), nodes, nil
+	}
+}
+
+func renderSessionNode(n sessionNode, width int) string {
+	switch {
+	case n.pane != nil:
+		row := fmt.Sprintf("%d: %-12s %s", n.pane.Index, n.pane.Command, n.pane.Path)
+		if n.pane.Active {
+			row += uiActiveMarker
+		}
+		return truncateRight(row, width)
+	case n.window != nil:
+		row := fmt.Sprintf("%d: %s (%d %s)", n.window.Index, n.window.Name, n.window.Panes, plural(n.window.Panes, "pane", "panes"))
+		if n.window.Active {
+			row += uiActiveMarker
+		}
+		return truncateRight(row, width)
+	default:
+		if n.session.Current {
+			return truncateRight(n.session.Name+uiCurrentMarker, width)
+		}
+		return truncateRight(n.session.Name, width)
+	}
+}
+
+func sessionNodeSearchText(n sessionNode) string {
+	switch {
+	case n.pane != nil:
+		return fmt.Sprintf("%d %s %s", n.pane.Index, n.pane.Command, n.pane.Path)
+	case n.window != nil:
+		return fmt.Sprintf("%d %s", n.window.Index, n.window.Name)
+	default:
+		return n.session.Name
+	}
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"golang.org/x/term"
 )
@@ -14,11 +15,43 @@ type selectorAdapter[T any] struct {
 	renderRow    func(item T, width int) string
 	summary      func(item T, width int) string
 	searchText   func(item T) string
-	directSelect func(query string) (*T, bool)
+	directSelect func(query string, items []T) (*T, bool)
+
+	// identity keys an item across refreshes so the cursor can follow it.
+	// Defaults to the search text when nil.
+	identity func(item T) string
+	// refresh, when set, is polled while the selector waits for input.
+	refresh func() ([]T, error)
+	// changed, when set, signals that the list changed and refresh should run
+	// now rather than at the next poll.
+	changed <-chan struct{}
+	// section, when set, groups consecutive items under a header row.
+	// Items must already be ordered by section.
+	section func(item T) string
+	// skipDefault, when set, keeps the default cursor position off matching
+	// items. They remain selectable with the arrow keys.
+	skipDefault func(item T) bool
+	// swatch, when set, returns an ANSI colour sequence drawn as a marker
+	// before each row, or "" for rows without a colour.
+	swatch func(item T) string
+	// children, when set, lets Right/Ctrl+L drill into an item and
+	// Left/Ctrl+H return.
+	// It returns the title and items of the child level; an empty list means
+	// the item cannot be opened.
+	children func(item T) (string, []T, error)
+}
+
+// selectorLevel is a saved drill-down level on the navigation stack.
+type selectorLevel[T any] struct {
+	title    string
+	items    []selectorItem[T]
+	query    string
+	selected int
 }
 
 type selectorItem[T any] struct {
 	value  T
+	id     string
 	search string
 }
 
@@ -29,6 +62,10 @@ const (
 
 	defaultTermWidth  = 80
 	defaultTermHeight = 24
+
+	selectorRefreshInterval = 2 * time.Second
+	// selectorChangeInterval is how often the changed channel is checked.
+	selectorChangeInterval = 100 * time.Millisecond
 )
 
 func runSelector[T any](items []T, adapter selectorAdapter[T]) (*T, error) {
@@ -36,65 +73,271 @@ func runSelector[T any](items []T, adapter selectorAdapter[T]) (*T, error) {
 		return nil, fmt.Errorf("no items")
 	}
 
-	prepared := make([]selectorItem[T], len(items))
-	for i, it := range items {
-		prepared[i] = selectorItem[T]{
-			value:  it,
-			search: strings.ToLower(adapter.searchText(it)),
-		}
-	}
+	state := newSelectorState(items, adapter)
 
-	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	fd := int(os.Stdin.Fd())
+	oldState, err := term.MakeRaw(fd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to set raw mode: %w", err)
 	}
-	defer term.Restore(int(os.Stdin.Fd()), oldState)
+	defer term.Restore(fd, oldState)
 
 	fmt.Print(ansiEnterAltScreen)
 	defer fmt.Print(ansiExitAltScreen)
 
-	query := ""
-	selected := 0
+	render := true
+	lastRefresh := time.Now()
+
 	for {
-		filtered := filterSelectorItems(prepared, query)
-		selected = clampSelected(selected, len(filtered))
-		renderSelector(filtered, query, selected, adapter)
+		filtered := state.filtered()
+		if render {
+			renderSelector(filtered, state.title, state.query, state.selected, adapter)
+		}
+		render = true
+
+		// Only the top level is refreshed; drilled-down levels are snapshots.
+		if adapter.refresh != nil && len(state.stack) == 0 {
+			ready, err := waitForInput(fd, adapter.pollInterval())
+			if err != nil {
+				return nil, fmt.Errorf("failed to read input: %w", err)
+			}
+			if !ready {
+				if !refreshDue(adapter.changed, lastRefresh) {
+					render = false
+					continue
+				}
+				lastRefresh = time.Now()
+				next, err := adapter.refresh()
+				if err != nil {
+					// Keep showing the last good list; the next poll may succeed.
+					render = false
+					continue
+				}
+				refreshed := prepareSelectorItems(next, adapter)
+				if sameSelectorItems(state.items, refreshed) {
+					render = false
+					continue
+				}
+				current := selectedIdentity(filtered, state.selected)
+				state.items = refreshed
+				state.selected = indexOfIdentity(filterSelectorItems(state.items, state.query), current, state.selected)
+				continue
+			}
+		}
 
 		ev, err := readKeyEvent()
 		if err != nil {
 			return nil, fmt.Errorf("failed to read input: %w", err)
 		}
-		switch ev.kind {
-		case keyCancel:
-			return nil, nil
-		case keyEnter:
-			if len(filtered) > 0 {
-				chosen := filtered[selected].value
-				return &chosen, nil
-			}
-		case keyBackspace:
-			if len(query) > 0 {
-				query = query[:len(query)-1]
-				selected = 0
-			}
-		case keyDown:
-			if selected < len(filtered)-1 {
-				selected++
-			}
-		case keyUp:
-			if selected > 0 {
-				selected--
+		if chosen, done := state.handleKey(ev, filtered); done {
+			return chosen, nil
+		}
+	}
+}
+
+// selectorState is the selector's current level, filter and cursor, with
+// the levels above it when drilled down.
+type selectorState[T any] struct {
+	adapter        selectorAdapter[T]
+	title          string
+	items          []selectorItem[T]
+	query          string
+	selected       int
+	resetSelection bool
+	stack          []selectorLevel[T]
+}
+
+func newSelectorState[T any](items []T, adapter selectorAdapter[T]) *selectorState[T] {
+	return &selectorState[T]{
+		adapter:        adapter,
+		title:          adapter.title,
+		items:          prepareSelectorItems(items, adapter),
+		resetSelection: true,
+	}
+}
+
+// filtered returns the items matching the query and settles the cursor on
+// them.
+func (s *selectorState[T]) filtered() []selectorItem[T] {
+	filtered := filterSelectorItems(s.items, s.query)
+	if s.resetSelection {
+		s.selected = defaultSelection(filtered, s.adapter.skipDefault)
+		s.resetSelection = false
+	}
+	s.selected = clampSelected(s.selected, len(filtered))
+	return filtered
+}
+
+// handleKey applies a key to the state. done is set when the selector
+// should close, with the chosen item or nil when cancelled. Printable keys
+// always go to the query; navigation is on arrows and Ctrl keys.
+func (s *selectorState[T]) handleKey(ev keyEvent, filtered []selectorItem[T]) (chosen *T, done bool) {
+	switch ev.kind {
+	case keyCancel:
+		return nil, true
+	case keyEnter:
+		if len(filtered) > 0 {
+			chosen := filtered[s.selected].value
+			return &chosen, true
+		}
+	case keyBackspace:
+		s.backspace()
+	case keyCtrlH:
+		// Backspace while there is a query to edit, otherwise Left.
+		if s.query != "" {
+			s.backspace()
+		} else {
+			s.ascend()
+		}
+	case keyDown:
+		if s.selected < len(filtered)-1 {
+			s.selected++
+		}
+	case keyUp:
+		if s.selected > 0 {
+			s.selected--
+		}
+	case keyRight:
+		if s.adapter.children != nil && len(filtered) > 0 {
+			s.descend(filtered[s.selected].value)
+		}
+	case keyLeft:
+		s.ascend()
+	case keyRune:
+		s.query += string(ev.r)
+		s.resetSelection = true
+		// Digits pick rows only at the top level; below it they filter.
+		if s.adapter.directSelect != nil && len(s.stack) == 0 {
+			if chosen, ok := s.adapter.directSelect(s.query, selectorValues(s.items)); ok {
+				return chosen, true
 			}
-		case keyRune:
-			query += string(ev.r)
-			selected = 0
-			if adapter.directSelect != nil {
-				if chosen, ok := adapter.directSelect(query); ok {
-					return chosen, nil
-				}
+		}
+	}
+	return nil, false
+}
+
+func (s *selectorState[T]) backspace() {
+	if len(s.query) > 0 {
+		s.query = s.query[:len(s.query)-1]
+		s.resetSelection = true
+	}
+}
+
+// descend opens item's children as a new level.
+func (s *selectorState[T]) descend(item T) {
+	childTitle, children, err := s.adapter.children(item)
+	if err != nil || len(children) == 0 {
+		return
+	}
+	s.stack = append(s.stack, selectorLevel[T]{title: s.title, items: s.items, query: s.query, selected: s.selected})
+	s.title = childTitle
+	s.items = prepareSelectorItems(children, s.adapter)
+	s.query = ""
+	s.resetSelection = true
+}
+
+// ascend returns to the level above, as it was left.
+func (s *selectorState[T]) ascend() {
+	if len(s.stack) == 0 {
+		return
+	}
+	top := s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+	s.title, s.items, s.query, s.selected = top.title, top.items, top.query, top.selected
+}
+
+// defaultSelection returns the first item not excluded by skip, or 0 when
+// every item is excluded.
+func defaultSelection[T any](items []selectorItem[T], skip func(T) bool) int {
+	if skip == nil {
+		return 0
+	}
+	for i, it := range items {
+		if !skip(it.value) {
+			return i
+		}
+	}
+	return 0
+}
+
+func prepareSelectorItems[T any](items []T, adapter selectorAdapter[T]) []selectorItem[T] {
+	prepared := make([]selectorItem[T], len(items))
+	for i, it := range items {
+		search := adapter.searchText(it)
+		id := search
+		if adapter.identity != nil {
+			id = adapter.identity(it)
+		}
+		prepared[i] = selectorItem[T]{
+			value:  it,
+			id:     id,
+			search: strings.ToLower(search),
+		}
+	}
+	return prepared
+}
+
+func selectorValues[T any](items []selectorItem[T]) []T {
+	values := make([]T, len(items))
+	for i, it := range items {
+		values[i] = it.value
+	}
+	return values
+}
+
+// pollInterval is how long to wait for a key before checking for changes.
+func (a selectorAdapter[T]) pollInterval() time.Duration {
+	if a.changed != nil {
+		return selectorChangeInterval
+	}
+	return selectorRefreshInterval
+}
+
+// refreshDue reports whether a poll timeout should refresh the list: when a
+// change was signalled, or when the regular interval has passed.
+func refreshDue(changed <-chan struct{}, last time.Time) bool {
+	select {
+	case _, ok := <-changed:
+		if ok {
+			return true
+		}
+	default:
+	}
+	return time.Since(last) >= selectorRefreshInterval
+}
+
+// sameSelectorItems reports whether a refresh produced the same list, so the
+// screen does not need to be redrawn.
+func sameSelectorItems[T any](a, b []selectorItem[T]) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].id != b[i].id || a[i].search != b[i].search {
+			return false
+		}
+	}
+	return true
+}
+
+func selectedIdentity[T any](items []selectorItem[T], selected int) string {
+	if selected < 0 || selected >= len(items) {
+		return ""
+	}
+	return items[selected].id
+}
+
+// indexOfIdentity finds the item with the given identity, falling back to the
+// previous index when the item disappeared.
+func indexOfIdentity[T any](items []selectorItem[T], id string, fallback int) int {
+	if id != "" {
+		for i, it := range items {
+			if it.id == id {
+				return i
 			}
 		}
 	}
+	return clampSelected(fallback, len(items))
 }
 
 func filterSelectorItems[T any](items []selectorItem[T], query string) []selectorItem[T] {
@@ -137,12 +380,12 @@ func currentTermSize() termSize {
 	return termSize{width: w, height: h}
 }
 
-func renderSelector[T any](items []selectorItem[T], query string, selected int, adapter selectorAdapter[T]) {
+func renderSelector[T any](items []selectorItem[T], title, query string, selected int, adapter selectorAdapter[T]) {
 	size := currentTermSize()
 	indent := selectorIndent
 
 	fmt.Print(ansiClearScreen)
-	fmt.Print(adapter.title)
+	fmt.Print(title)
 	fmt.Print(crlf)
 	fmt.Print(crlf)
 
@@ -168,23 +411,43 @@ func renderSelector[T any](items []selectorItem[T], query string, selected int,
 		return
 	}
 
-	start, end := visibleRange(len(items), selected, maxRows)
-	for i := start; i < end; i++ {
+	rows, selectedRow := buildSelectorRows(items, selected, adapter.section)
+	start, end := visibleRange(len(rows), selectedRow, maxRows)
+	for r := start; r < end; r++ {
 		rowWidth := size.width - indent
 		if rowWidth < 0 {
 			rowWidth = 0
 		}
+		if rows[r].header != "" {
+			fmt.Print(" ")
+			fmt.Print(ansiDim)
+			fmt.Print(truncateRight(rows[r].header, size.width-1))
+			fmt.Print(ansiReset)
+			fmt.Print(crlf)
+			continue
+		}
+		i := rows[r].item
+		marker := ""
+		if adapter.swatch != nil {
+			rowWidth -= len(uiSwatchBlank)
+			marker = uiSwatchBlank
+			if colour := adapter.swatch(items[i].value); colour != "" {
+				marker = colour + uiSwatch + ansiDefaultFg + " "
+			}
+		}
 		line := adapter.renderRow(items[i].value, rowWidth)
 		if i == selected {
 			fmt.Print(spaces(indent - 1))
 			fmt.Print(ansiInvert)
 			fmt.Print(" ")
+			fmt.Print(marker)
 			fmt.Print(truncateRight(line, rowWidth))
 			fmt.Print(" ")
 			fmt.Print(ansiReset)
 			fmt.Print(crlf)
 		} else {
 			fmt.Print(spaces(indent))
+			fmt.Print(marker)
 			fmt.Print(truncateRight(line, rowWidth))
 			fmt.Print(crlf)
 		}
@@ -216,6 +479,35 @@ func renderSelector[T any](items []selectorItem[T], query string, selected int,
 	fmt.Print(query)
 }
 
+// selectorRow is a rendered line: either a section header or an item.
+// Headers are never selectable; the cursor always indexes items.
+type selectorRow struct {
+	header string
+	item   int
+}
+
+// buildSelectorRows interleaves section headers with items and returns the
+// row index of the selected item. Sections without items never get a header.
+func buildSelectorRows[T any](items []selectorItem[T], selected int, section func(T) string) ([]selectorRow, int) {
+	rows := make([]selectorRow, 0, len(items))
+	selectedRow := 0
+	last := ""
+	for i, it := range items {
+		if section != nil {
+			label := section(it.value)
+			if label != "" && (i == 0 || label != last) {
+				rows = append(rows, selectorRow{header: label, item: -1})
+			}
+			last = label
+		}
+		if i == selected {
+			selectedRow = len(rows)
+		}
+		rows = append(rows, selectorRow{item: i})
+	}
+	return rows, selectedRow
+}
+
 func visibleRange(total, selected, maxRows int) (int, int) {
 	if total <= 0 {
 		return 0, 0
@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"testing"
+	"time"
+)
+
+func testAdapter() selectorAdapter[string] {
+	return selectorAdapter[string]{
+		searchText: func(s string) string { return s },
+	}
+}
+
+func TestIndexOfIdentityFollowsItemAcrossRefresh(t *testing.T) {
+	adapter := testAdapter()
+	before := prepareSelectorItems([]string{"api", "web", "docs"}, adapter)
+	current := selectedIdentity(before, 1)
+
+	after := prepareSelectorItems([]string{"new", "api", "docs", "web"}, adapter)
+	if got := indexOfIdentity(after, current, 1); got != 3 {
+		t.Fatalf("expected selection to follow %q to index 3, got %d", current, got)
+	}
+}
+
+func TestIndexOfIdentityFallsBackWhenItemDisappears(t *testing.T) {
+	adapter := testAdapter()
+	after := prepareSelectorItems([]string{"api"}, adapter)
+	if got := indexOfIdentity(after, "web", 1); got != 0 {
+		t.Fatalf("expected clamped fallback 0, got %d", got)
+	}
+}
+
+func TestSameSelectorItems(t *testing.T) {
+	adapter := testAdapter()
+	a := prepareSelectorItems([]string{"api", "web"}, adapter)
+	b := prepareSelectorItems([]string{"api", "web"}, adapter)
+	if !sameSelectorItems(a, b) {
+		t.Fatalf("expected identical lists to compare equal")
+	}
+	c := prepareSelectorItems([]string{"web", "api"}, adapter)
+	if sameSelectorItems(a, c) {
+		t.Fatalf("expected reordered lists to differ")
+	}
+}
+
+func TestBuildSelectorRowsInsertsHeadersForNonEmptySections(t *testing.T) {
+	adapter := testAdapter()
+	items := prepareSelectorItems([]string{"a1", "a2", "b1"}, adapter)
+	section := func(s string) string { return s[:1] }
+
+	rows, selectedRow := buildSelectorRows(items, 2, section)
+	if len(rows) != 5 {
+		t.Fatalf("expected 5 rows (2 headers, 3 items), got %d", len(rows))
+	}
+	if rows[0].header != "a" || rows[3].header != "b" {
+		t.Fatalf("unexpected headers: %+v", rows)
+	}
+	if selectedRow != 4 || rows[selectedRow].item != 2 {
+		t.Fatalf("selected row mismatch: got %d", selectedRow)
+	}
+
+	filtered := filterSelectorItems(items, "b")
+	rows, _ = buildSelectorRows(filtered, 0, section)
+	if len(rows) != 2 || rows[0].header != "b" {
+		t.Fatalf("expected empty section to be hidden, got %+v", rows)
+	}
+}
+
+func TestDefaultSelectionSkipsExcludedItems(t *testing.T) {
+	items := prepareSelectorItems([]string{"current", "api", "web"}, testAdapter())
+	skip := func(s string) bool { return s == "current" }
+	if got := defaultSelection(items, skip); got != 1 {
+		t.Fatalf("expected default selection 1, got %d", got)
+	}
+	only := prepareSelectorItems([]string{"current"}, testAdapter())
+	if got := defaultSelection(only, skip); got != 0 {
+		t.Fatalf("expected fallback to 0, got %d", got)
+	}
+}
+
+func TestRefreshDue(t *testing.T) {
+	changed := make(chan struct{}, 1)
+	if refreshDue(changed, time.Now()) {
+		t.Fatalf("expected no refresh before a change or the interval")
+	}
+	changed <- struct{}{}
+	if !refreshDue(changed, time.Now()) {
+		t.Fatalf("expected refresh after a change")
+	}
+	if !refreshDue(nil, time.Now().Add(-selectorRefreshInterval)) {
+		t.Fatalf("expected refresh once the interval passed")
+	}
+}
+
+func TestSelectorTypingHAndLFilters(t *testing.T) {
+	adapter := testAdapter()
+	adapter.children = func(s string) (string, []string, error) {
+		return s, []string{s + ":0", s + ":1"}, nil
+	}
+	state := newSelectorState([]string{"api", "home", "logs"}, adapter)
+
+	state.handleKey(keyEvent{kind: keyRune, r: 'h'}, state.filtered())
+	filtered := state.filtered()
+	if state.query != "h" || len(state.stack) != 0 {
+		t.Fatalf("expected h to start a filter, got query %q at depth %d", state.query, len(state.stack))
+	}
+	if len(filtered) != 1 || filtered[0].value != "home" {
+		t.Fatalf("expected h to match home, got %+v", filtered)
+	}
+
+	state.handleKey(keyEvent{kind: keyBackspace}, filtered)
+	state.handleKey(keyEvent{kind: keyRune, r: 'l'}, state.filtered())
+	if filtered := state.filtered(); state.query != "l" || len(filtered) != 1 || filtered[0].value != "logs" {
+		t.Fatalf("expected l to match logs, got query %q and %+v", state.query, filtered)
+	}
+}
+
+func TestSelectorDrillsDownOnRightAndLeft(t *testing.T) {
+	adapter := testAdapter()
+	adapter.children = func(s string) (string, []string, error) {
+		return s, []string{s + ":0", s + ":1"}, nil
+	}
+	state := newSelectorState([]string{"api", "web"}, adapter)
+
+	state.handleKey(keyEvent{kind: keyDown}, state.filtered())
+	state.handleKey(keyEvent{kind: keyRight}, state.filtered())
+	if filtered := state.filtered(); state.title != "web" || len(filtered) != 2 || filtered[0].value != "web:0" {
+		t.Fatalf("expected web's children, got %q %+v", state.title, filtered)
+	}
+	state.handleKey(keyEvent{kind: keyLeft}, state.filtered())
+	if filtered := state.filtered(); len(state.stack) != 0 || filtered[state.selected].value != "web" {
+		t.Fatalf("expected to return to web, got depth %d selected %d", len(state.stack), state.selected)
+	}
+}
+
+func TestSelectorDigitsFilterBelowTopLevel(t *testing.T) {
+	adapter := testAdapter()
+	adapter.children = func(s string) (string, []string, error) {
+		return s, []string{"0: home", "1: cmd"}, nil
+	}
+	adapter.directSelect = func(query string, items []string) (*string, bool) {
+		return &items[0], true
+	}
+	state := newSelectorState([]string{"api"}, adapter)
+	state.handleKey(keyEvent{kind: keyRight}, state.filtered())
+
+	if chosen, done := state.handleKey(keyEvent{kind: keyRune, r: '1'}, state.filtered()); done {
+		t.Fatalf("expected 1 to filter windows, chose %v", chosen)
+	}
+	if filtered := state.filtered(); len(filtered) != 1 || filtered[0].value != "1: cmd" {
+		t.Fatalf("expected 1 to match window 1, got %+v", filtered)
+	}
+}
+
+func TestSelectorCtrlHIsBackspaceWhileTyping(t *testing.T) {
+	adapter := testAdapter()
+	adapter.children = func(s string) (string, []string, error) {
+		return s, []string{s + ":0"}, nil
+	}
+	state := newSelectorState([]string{"api"}, adapter)
+	state.handleKey(keyEvent{kind: keyRight}, state.filtered())
+	state.handleKey(keyEvent{kind: keyRune, r: '0'}, state.filtered())
+
+	state.handleKey(keyEvent{kind: keyCtrlH}, state.filtered())
+	if state.query != "" || len(state.stack) != 1 {
+		t.Fatalf("expected Ctrl+H to delete the query, got %q at depth %d", state.query, len(state.stack))
+	}
+	state.handleKey(keyEvent{kind: keyCtrlH}, state.filtered())
+	if len(state.stack) != 0 {
+		t.Fatalf("expected Ctrl+H on an empty query to go back up")
+	}
+}
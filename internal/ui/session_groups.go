@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+const envGroups = "P_GROUPS"
+
+// sessionGroup is a user-defined section of the session list.
+type sessionGroup struct {
+	label    string
+	patterns []string
+}
+
+// parseSessionGroups parses P_GROUPS, a semicolon-separated list of
+// label=pattern[,pattern...] entries. Patterns use filepath.Match syntax.
+//
+//	P_GROUPS='work=api*,web;personal=blog,notes'
+func parseSessionGroups(spec string) ([]sessionGroup, error) {
+	var groups []sessionGroup
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		label, list, ok := strings.Cut(entry, "=")
+		label = strings.TrimSpace(label)
+		if !ok || label == "" {
+			return nil, fmt.Errorf("invalid %s entry %q: expected label=pattern[,pattern]", envGroups, entry)
+		}
+		group := sessionGroup{label: label}
+		for _, pattern := range strings.Split(list, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid %s pattern %q in group %q", envGroups, pattern, label)
+			}
+			group.patterns = append(group.patterns, pattern)
+		}
+		if len(group.patterns) == 0 {
+			return nil, fmt.Errorf("%s group %q has no patterns", envGroups, label)
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (g sessionGroup) matches(name string) bool {
+	for _, pattern := range g.patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionSections assigns each session a section and orders sessions so that
+// sections appear as: current, user-defined groups, attached, detached.
+type sessionSections struct {
+	groups []sessionGroup
+}
+
+func loadSessionSections() (sessionSections, error) {
+	groups, err := parseSessionGroups(os.Getenv(envGroups))
+	if err != nil {
+		return sessionSections{}, err
+	}
+	return sessionSections{groups: groups}, nil
+}
+
+func (s sessionSections) rank(session tmux.Session) (int, string) {
+	if session.Current {
+		return 0, uiSectionCurrent
+	}
+	for i, g := range s.groups {
+		if g.matches(session.Name) {
+			return i + 1, g.label
+		}
+	}
+	if session.Attached {
+		return len(s.groups) + 1, uiSectionAttached
+	}
+	return len(s.groups) + 2, uiSectionDetached
+}
+
+func (s sessionSections) label(session tmux.Session) string {
+	_, label := s.rank(session)
+	return label
+}
+
+func (s sessionSections) sort(sessions []tmux.Session) []tmux.Session {
+	sorted := append([]tmux.Session(nil), sessions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ri, _ := s.rank(sorted[i])
+		rj, _ := s.rank(sorted[j])
+		return ri < rj
+	})
+	return sorted
+}
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/wilmoore/p/internal/tmux"
+)
+
+func TestParseSessionGroups(t *testing.T) {
+	groups, err := parseSessionGroups("work=api*,web; personal=blog")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 2 || groups[0].label != "work" || len(groups[0].patterns) != 2 {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+
+	for _, bad := range []string{"work", "=api", "work=", "work=[api"} {
+		if _, err := parseSessionGroups(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestSessionSectionsOrdering(t *testing.T) {
+	groups, err := parseSessionGroups("work=api*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sections := sessionSections{groups: groups}
+	sessions := []tmux.Session{
+		{Name: "notes"},
+		{Name: "blog", Attached: true},
+		{Name: "api-v2"},
+		{Name: "home", Current: true, Attached: true},
+	}
+
+	sorted := sections.sort(sessions)
+	want := []string{"home", "api-v2", "blog", "notes"}
+	for i, name := range want {
+		if sorted[i].Name != name {
+			t.Fatalf("position %d: got %q want %q", i, sorted[i].Name, name)
+		}
+	}
+	labels := []string{uiSectionCurrent, "work", uiSectionAttached, uiSectionDetached}
+	for i, label := range labels {
+		if got := sections.label(sorted[i]); got != label {
+			t.Fatalf("section %d: got %q want %q", i, got, label)
+		}
+	}
+}
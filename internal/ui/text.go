@@ -4,9 +4,26 @@ const (
 	uiTitleSessions = "Sessions:"
 	uiTitleHistory  = "Session History:"
 
+	uiTitleWindowsFmt = "Windows in %s:"
+	uiTitlePanesFmt   = "Panes in %s:"
+
 	uiNoMatches = "  (no matches)"
 	uiSelected  = "Selected:"
 	uiPrompt    = "> "
 
 	uiSelectedNone = "-"
+
+	uiSectionCurrent  = "Current"
+	uiSectionAttached = "Attached"
+	uiSectionDetached = "Detached"
+
+	uiCurrentMarker = " (current)"
+	uiActiveMarker  = " *"
+
+	uiServerLabelFmt = "[%s] "
+
+	// uiSwatch marks a session's accent colour; uiSwatchBlank keeps rows
+	// without one aligned.
+	uiSwatch      = "●"
+	uiSwatchBlank = "  "
 )
@@ -1,18 +1,28 @@
 package main
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/wilmoore/p/internal/clierr"
+	"github.com/wilmoore/p/internal/git"
 	"github.com/wilmoore/p/internal/history"
 	"github.com/wilmoore/p/internal/i18n"
+	"github.com/wilmoore/p/internal/naming"
+	"github.com/wilmoore/p/internal/project"
+	"github.com/wilmoore/p/internal/snapshot"
+	"github.com/wilmoore/p/internal/template"
 	"github.com/wilmoore/p/internal/tmux"
 	"github.com/wilmoore/p/internal/ui"
+	"golang.org/x/term"
 )
 
 // Version is set at build time via -ldflags "-X main.Version=vX.Y.Z"
@@ -23,15 +33,47 @@ const usage = `p - minimal tmux session switcher
 Usage:
   p                          Show interactive session selector
   p <path>                   Create new session in directory (use . for current directory)
-  p <path> --name <custom>   Create session with a custom name
+  p <url|owner/repo>         Clone a repository into P_SRC_ROOT (default ~/src)
+                             unless already there, and create a session in it
+  p <path> --name <custom>   Create session with a custom name, or a naming
+                             template such as {repo}-{branch}
+  p <path> --no-template     Create session ignoring the project's .p template
+  p <path> --dry-run         Show the session p would create, without creating it
+  p <path> --root            Root the session at the project containing <path>
+                             (also: P_ROOT=1)
+  p @<session>[:<window>[.<pane>]]
+                             Attach directly to a session, window or pane
   p --log                    Browse session history ledger
+  p --unstyle <session>      Remove p's styling from a session, restoring its
+                             previous options
+  p --save-template <session> [path] [--commands] [--force]
+                             Save a session's windows and panes as a .p
+                             template (default: in the session's directory)
+  p --worktree <branch>      Open a session in a worktree of the current
+                             repository, creating it if needed
+  p --worktree-remove <branch> [--force]
+                             Remove the branch's worktree and kill its session
+  p --snapshot [--scrollback]
+                             Save every session, optionally with scrollback
+  p --restore [file]         Rebuild the sessions of the latest (or given)
+                             snapshot, skipping those already running
   p --version                Show version information
   p --help                   Show this help message
 
+Options:
+  --socket <name|path>       Use another tmux server (-L name, or -S path if it
+                             contains a slash). Repeat to list sessions from
+                             several servers. Also: -L <name>, -S <path>,
+                             P_TMUX_SOCKET=<name|path>[,...]
+  --reconfigure              Re-apply p's styling even if the session already
+                             has the current version
+
 Navigation:
   Type           Filter sessions by name
   Arrow keys     Navigate up/down
-  Enter          Attach to selected session
+  Right/Ctrl+L   Open a session's windows, or a window's panes
+  Left/Ctrl+H    Go back up a level
+  Enter          Attach to selected session, window or pane
   Esc/Ctrl+C     Cancel
 
 Examples:
@@ -39,6 +81,8 @@ Examples:
   p .            Create session in current directory
   p ~/projects   Create session in ~/projects
   p ./revenue --name savvy-revenue
+  p @api:2       Attach to window 2 of session "api"
+  p @api:logs.1  Attach to pane 1 of the "logs" window
   p --log        Inspect or relaunch recent sessions
 `
 
@@ -55,6 +99,7 @@ func run() error {
 		return err
 	}
 
+	// These never talk to tmux, so configuration errors don't stop them.
 	switch cmd.kind {
 	case commandVersion:
 		fmt.Println(Version)
@@ -62,35 +107,209 @@ func run() error {
 	case commandHelp:
 		fmt.Print(usage)
 		return nil
+	}
+
+	servers := cmd.servers
+	if len(servers) == 0 {
+		if servers, err = serversFromEnv(); err != nil {
+			return err
+		}
+	}
+	if len(servers) > 0 {
+		tmux.SetServer(servers[0])
+	}
+	tmux.SetForceConfigure(cmd.reconfigure)
+	if err := checkConfig(); err != nil {
+		return err
+	}
+
+	switch cmd.kind {
 	case commandHistory:
 		return showHistory()
 	case commandCreate:
-		return createSessionFromPath(cmd.path, cmd.sessionName)
+		if cmd.dryRun {
+			return dryRun(cmd.path, cmd.sessionName, cmd.createOptions())
+		}
+		return createSessionFromPath(cmd.path, cmd.sessionName, cmd.createOptions())
+	case commandClone:
+		return openRemote(cmd.remote, cmd.path, cmd.sessionName, cmd.createOptions(), cmd.dryRun)
+	case commandAttach:
+		return attachToTarget(cmd.target, servers)
+	case commandUnstyle:
+		return tmux.UnstyleSession(cmd.sessionName)
+	case commandSaveTemplate:
+		return saveTemplate(cmd.sessionName, cmd.path, cmd.withCommands, cmd.force)
+	case commandWorktree:
+		return openWorktree(cmd.branch)
+	case commandWorktreeRemove:
+		return removeWorktree(cmd.branch, cmd.force)
+	case commandSnapshot:
+		return takeSnapshot(cmd.scrollback)
+	case commandRestore:
+		return restoreSnapshot(cmd.path)
 	case commandSelector:
-		return showSessionSelector()
+		return showSessionSelector(servers)
 	default:
 		return fmt.Errorf("unknown command")
 	}
 }
 
-func showSessionSelector() error {
-	sessions, err := tmux.ListSessions()
-	if err != nil && !tmux.IsNoServerError(err) {
-		return fmt.Errorf("failed to list tmux sessions: %w", err)
+// checkConfig validates the environment variables that shape tmux
+// sessions, so a mistake is reported before any session is touched.
+func checkConfig() error {
+	if _, err := tmux.ConfigFile(); err != nil {
+		return err
+	}
+	if err := tmux.CheckStatusConfig(); err != nil {
+		return err
+	}
+	if err := tmux.CheckAccent(); err != nil {
+		return err
+	}
+	_, err := tmux.LoadCollisionPolicy()
+	return err
+}
+
+// openControl routes tmux calls over a control-mode connection while the
+// selector is open, so polling reuses one tmux client and session changes
+// are pushed instead of waited for. Falls back to running tmux per command
+// when the connection cannot be made or sessions span several servers.
+func openControl(servers []tmux.Server) (<-chan struct{}, func()) {
+	if len(servers) > 1 {
+		return nil, func() {}
+	}
+	client, err := tmux.DialControl()
+	if err != nil {
+		if clierr.DebugEnabled() {
+			fmt.Fprintf(os.Stderr, "debug: tmux control mode unavailable: %v\n", err)
+		}
+		return nil, func() {}
+	}
+	restore := tmux.SetRunner(client)
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		for n := range client.Notifications() {
+			switch n.Name {
+			case "sessions-changed", "session-renamed", "client-session-changed", "client-detached":
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changes, func() {
+		restore()
+		client.Close()
+	}
+}
+
+func showSessionSelector(servers []tmux.Server) error {
+	listSessions := func() ([]tmux.Session, error) {
+		return listSessions(servers)
+	}
+	sessions, err := listSessions()
+	if err != nil {
+		return err
 	}
 
 	if len(sessions) == 0 {
 		return fmt.Errorf(i18n.ErrNoTmuxSessionsAvailable)
 	}
 
-	choice, err := ui.ShowSelector(sessions)
+	changes, closeControl := openControl(servers)
+	choice, err := ui.ShowSelector(sessions, listSessions, changes)
+	// Drop the control connection before attaching replaces the process.
+	closeControl()
 	if err != nil {
 		return err
 	}
 	if choice == nil {
 		return nil
 	}
-	return attachAndLog(choice.Name, history.ActionAttach)
+	return attachAndLog(*choice, history.ActionAttach)
+}
+
+// attachToTarget attaches to a session:window.pane target given on the command line.
+// With several servers, the first one that has the session wins.
+func attachToTarget(target tmux.Target, servers []tmux.Server) error {
+	if len(servers) <= 1 {
+		resolved, err := tmux.ResolveTarget(target)
+		if err != nil {
+			return err
+		}
+		return attachAndLog(resolved, history.ActionAttach)
+	}
+	var firstErr error
+	for _, s := range servers {
+		target.Server = s
+		var resolved tmux.Target
+		err := tmux.WithServer(s, func() (err error) {
+			resolved, err = tmux.ResolveTarget(target)
+			return err
+		})
+		if err == nil {
+			return attachAndLog(resolved, history.ActionAttach)
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// listSessions lists tmux sessions, treating a missing server as no sessions.
+// More than one server produces an aggregated list.
+// The current session is dropped when P_HIDE_CURRENT is enabled.
+func listSessions(servers []tmux.Server) ([]tmux.Session, error) {
+	var sessions []tmux.Session
+	var err error
+	if len(servers) > 1 {
+		sessions, err = tmux.ListSessionsOn(servers)
+	} else {
+		sessions, err = tmux.ListSessions()
+	}
+	if err != nil && !tmux.IsNoServerError(err) {
+		return nil, fmt.Errorf("failed to list tmux sessions: %w", err)
+	}
+	if !envEnabled(envHideCurrent) {
+		return sessions, nil
+	}
+	visible := sessions[:0]
+	for _, s := range sessions {
+		if !s.Current {
+			visible = append(visible, s)
+		}
+	}
+	return visible, nil
+}
+
+const (
+	envHideCurrent = "P_HIDE_CURRENT"
+	envTmuxSocket  = "P_TMUX_SOCKET"
+)
+
+// serversFromEnv reads P_TMUX_SOCKET, a comma-separated list of socket
+// names or paths.
+func serversFromEnv() ([]tmux.Server, error) {
+	var servers []tmux.Server
+	for _, value := range strings.Split(os.Getenv(envTmuxSocket), ",") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		s, err := tmux.ParseServer(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", envTmuxSocket, err)
+		}
+		servers = append(servers, s)
+	}
+	return servers, nil
+}
+
+func envEnabled(name string) bool {
+	v := strings.TrimSpace(strings.ToLower(os.Getenv(name)))
+	return v == "1" || v == "true" || v == "yes" || v == "on"
 }
 
 func showHistory() error {
@@ -112,16 +331,46 @@ func showHistory() error {
 	if choice.TargetDir == "" {
 		return fmt.Errorf(i18n.ErrHistoryMissingTargetDir)
 	}
-	return createSessionFromPath(choice.TargetDir, choice.SessionName)
+	relaunch := func() error {
+		return createSessionFromPath(choice.TargetDir, choice.SessionName, createOptions{template: true})
+	}
+	if choice.Server == "" {
+		return relaunch()
+	}
+	s, err := tmux.ParseServer(choice.Server)
+	if err != nil {
+		return err
+	}
+	return tmux.WithServer(s, relaunch)
+}
+
+// createOptions shape how createSessionFromPath builds a session.
+type createOptions struct {
+	// template uses a .p template in the directory or at its git root for
+	// the session's name and layout.
+	template bool
+	// root roots the session at the project containing the directory.
+	root bool
+	// untrusted builds the session without the commands and env of its
+	// template or detected layout, as for a fresh clone.
+	untrusted bool
 }
 
 // createSessionFromPath creates (or attaches to) a tmux session in the specified directory.
-func createSessionFromPath(path, overrideName string) error {
-	spec, err := buildSessionSpec(path, overrideName)
+func createSessionFromPath(path, overrideName string, opts createOptions) error {
+	spec, err := buildSessionSpec(path, overrideName, opts.root)
 	if err != nil {
 		return err
 	}
-	action, err := tmux.CreateSession(spec.sessionName, spec.workingDir)
+	layout, _, err := sessionLayout(spec, overrideName, opts, true)
+	if err != nil {
+		return err
+	}
+	layout = startIn(layout, spec.startDir)
+	if err := nameSession(spec, overrideName != "", true); err != nil {
+		return err
+	}
+	action, err := tmux.CreateSession(spec.sessionName, spec.workingDir, layout)
 	if err != nil {
 		return err
 	}
@@ -129,16 +378,491 @@ func createSessionFromPath(path, overrideName string) error {
 	if action == tmux.LaunchActionAttachExisting {
 		logAction = history.ActionAttachExisting
 	}
-	logLaunch(logAction, spec.sessionName, spec.workingDir)
-	return tmux.AttachToSession(spec.sessionName)
+	logLaunch(logAction, spec.sessionName, spec.workingDir, tmux.Server{})
+	return tmux.AttachToSession(tmux.Target{Session: spec.sessionName})
+}
+
+// envDetect enables the built-in layouts for recognised project types.
+const envDetect = "P_DETECT"
+
+// envRoot turns on project root detection for every path, like --root.
+const envRoot = "P_ROOT"
+
+// sessionLayout returns the layout for a new session and where it came
+// from: the project's template, else the layout detected from its files
+// when P_DETECT is enabled, else P_WINDOWS. The template's name, which may
+// be a naming template, replaces the directory's unless --name was given.
+// The commands and env of a template or detected layout only run once it is
+// trusted; interactive allows asking.
+func sessionLayout(spec *sessionSpec, overrideName string, opts createOptions, interactive bool) (tmux.Layout, string, error) {
+	var tpl *template.Template
+	var source string
+	var approval templateApproval
+	var err error
+	if opts.template {
+		if tpl, err = template.Load(spec.workingDir); err != nil {
+			return tmux.Layout{}, "", err
+		}
+		if tpl != nil {
+			path, _ := template.Find(spec.workingDir)
+			source = "template " + path
+			approval = templateApproval{
+				label:   path,
+				trusted: func() (bool, error) { return template.Trusted(path) },
+				trust:   func() error { return template.Trust(path) },
+			}
+		} else if envEnabled(envDetect) {
+			var found []string
+			if tpl, found = template.Detect(spec.workingDir); tpl != nil {
+				source = "detected from " + strings.Join(found, ", ")
+				detected := tpl
+				approval = templateApproval{
+					label:   fmt.Sprintf(i18n.MsgDetectedLayoutFmt, spec.workingDir),
+					trusted: func() (bool, error) { return template.TrustedDetected(spec.workingDir, detected) },
+					trust:   func() error { return template.TrustDetected(spec.workingDir, detected) },
+				}
+			}
+		}
+		switch {
+		case tpl == nil || !tpl.NeedsTrust():
+		case opts.untrusted:
+			// A fresh clone is someone else's code: run none of it until
+			// the project has been looked at.
+			tpl = tpl.WithoutCommands()
+			source += " without commands (new clone)"
+		default:
+			trusted, err := trustTemplate(approval, tpl, interactive)
+			if err != nil {
+				return tmux.Layout{}, "", err
+			}
+			switch {
+			case !trusted && interactive:
+				tpl = tpl.WithoutCommands()
+				source += " without commands (not trusted)"
+			case !trusted:
+				source += " (not trusted yet; p asks before running its commands)"
+			}
+		}
+	}
+	if tpl == nil {
+		layout, err := tmux.DefaultLayout()
+		return layout, "P_WINDOWS", err
+	}
+	if overrideName == "" && tpl.Name != "" {
+		spec.sessionName = tpl.Name
+		if strings.Contains(tpl.Name, "{") {
+			if spec.sessionName, err = naming.Expand(tpl.Name, spec.workingDir); err != nil {
+				return tmux.Layout{}, "", err
+			}
+		}
+	}
+	var fallback tmux.Layout
+	if len(tpl.Windows) == 0 {
+		if fallback, err = tmux.DefaultLayout(); err != nil {
+			return tmux.Layout{}, "", err
+		}
+		source += " with P_WINDOWS"
+	}
+	return tpl.Layout(fallback), source, nil
+}
+
+// templateApproval checks and records trust in a template's commands: the
+// .p file's, or a detected layout's. label names it in prompts.
+type templateApproval struct {
+	label   string
+	trusted func() (bool, error)
+	trust   func() error
+}
+
+// trustTemplate reports whether tpl may run its commands. An unapproved
+// template is shown and approved on request when interactive and stdin is a
+// terminal; otherwise it is left untrusted, with a warning when interactive.
+func trustTemplate(approval templateApproval, tpl *template.Template, interactive bool) (bool, error) {
+	trusted, err := approval.trusted()
+	if err != nil || trusted || !interactive {
+		return trusted, err
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintf(os.Stderr, i18n.WarnUntrustedTemplateFmt, approval.label)
+		return false, nil
+	}
+	if !promptTrust(os.Stdin, os.Stderr, approval.label, tpl) {
+		return false, nil
+	}
+	return true, approval.trust()
+}
+
+// promptTrust shows what the template would run and asks whether to allow
+// it. Anything but yes declines.
+func promptTrust(in io.Reader, out io.Writer, label string, tpl *template.Template) bool {
+	fmt.Fprintf(out, i18n.MsgTemplateWantsToRunFmt, label)
+	for _, key := range slices.Sorted(maps.Keys(tpl.Env)) {
+		fmt.Fprintf(out, i18n.MsgTemplateEnvFmt, key, tpl.Env[key])
+	}
+	for _, w := range tpl.Windows {
+		for _, p := range w.Panes {
+			if p.Command != "" {
+				fmt.Fprintf(out, i18n.MsgTemplateCommandFmt, w.Name, p.Command)
+			}
+		}
+	}
+	fmt.Fprint(out, i18n.MsgTrustTemplatePrompt)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// nameSession makes the session name safe for tmux. Unless the name was
+// given explicitly, a clash with a session open in another directory is
+// resolved as P_COLLISION says; interactive allows the prompt policy to ask.
+func nameSession(spec *sessionSpec, explicit, interactive bool) error {
+	spec.sessionName = tmux.SanitizeName(spec.sessionName)
+	if explicit {
+		return nil
+	}
+	policy, err := tmux.LoadCollisionPolicy()
+	if err != nil {
+		return err
+	}
+	name, err := tmux.SessionName(spec.sessionName, spec.workingDir, policy)
+	var collision *tmux.NameCollisionError
+	if errors.As(err, &collision) && policy == tmux.CollisionPrompt && interactive && term.IsTerminal(int(os.Stdin.Fd())) {
+		name, err = promptSessionName(os.Stdin, os.Stderr, collision)
+	}
+	if err != nil {
+		return err
+	}
+	spec.sessionName = name
+	return nil
+}
+
+// promptSessionName asks for a name until it gets a free one. An empty
+// answer takes the suggestion.
+func promptSessionName(in io.Reader, out io.Writer, collision *tmux.NameCollisionError) (string, error) {
+	fmt.Fprintf(out, i18n.MsgSessionOpenElsewhereFmt, collision.Name, collision.Existing)
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(out, i18n.MsgSessionNamePromptFmt, collision.Dir, collision.Suggestion)
+		if !scanner.Scan() {
+			return "", collision
+		}
+		name := tmux.SanitizeName(scanner.Text())
+		if name == "" {
+			name = collision.Suggestion
+		}
+		if name == "" {
+			continue
+		}
+		available, err := tmux.NameAvailable(name, collision.Dir)
+		if err != nil {
+			return "", err
+		}
+		if available {
+			return name, nil
+		}
+		fmt.Fprintf(out, i18n.MsgSessionNameTakenFmt, name)
+	}
+}
+
+// dryRun prints the session createSessionFromPath would create.
+func dryRun(path, overrideName string, opts createOptions) error {
+	spec, err := buildSessionSpec(path, overrideName, opts.root)
+	if err != nil {
+		return err
+	}
+	layout, source, err := sessionLayout(spec, overrideName, opts, false)
+	if err != nil {
+		return err
+	}
+	layout = startIn(layout, spec.startDir)
+	var collision *tmux.NameCollisionError
+	if err := nameSession(spec, overrideName != "", false); errors.As(err, &collision) {
+		spec.sessionName = fmt.Sprintf("%s (open in %s; p would ask for a name, suggesting %s)", collision.Name, collision.Existing, collision.Suggestion)
+	} else if err != nil {
+		return err
+	}
+	if opts.template && source == "P_WINDOWS" && !envEnabled(envDetect) {
+		if _, found := template.Detect(spec.workingDir); len(found) > 0 {
+			source += fmt.Sprintf(" (%s found; set %s=1 to use them)", strings.Join(found, ", "), envDetect)
+		}
+	}
+	writePlan(os.Stdout, spec, layout, source)
+	return nil
+}
+
+// writePlan describes a session and its layout, one window per line with
+// its panes indented below.
+func writePlan(w io.Writer, spec *sessionSpec, layout tmux.Layout, source string) {
+	fmt.Fprintf(w, i18n.MsgPlanSessionFmt, spec.sessionName)
+	fmt.Fprintf(w, i18n.MsgPlanDirectoryFmt, spec.workingDir)
+	fmt.Fprintf(w, i18n.MsgPlanLayoutFmt, source)
+	for _, key := range slices.Sorted(maps.Keys(layout.Env)) {
+		fmt.Fprintf(w, i18n.MsgPlanEnvFmt, key, layout.Env[key])
+	}
+	focus := layout.Focus
+	for i, win := range layout.Windows {
+		marker := " "
+		if win.Name == focus || (focus == "" && i == 0) {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s %s", marker, win.Name)
+		if win.Arrangement != "" {
+			fmt.Fprintf(w, " @%s", win.Arrangement)
+		}
+		fmt.Fprintln(w)
+		for _, pane := range win.Panes {
+			dir := pane.Dir
+			if dir == "" {
+				dir = "."
+			}
+			command := pane.Command
+			if command == "" {
+				command = i18n.MsgPlanShell
+			}
+			fmt.Fprintf(w, i18n.MsgPlanPaneFmt, dir, command)
+		}
+	}
+}
+
+// saveTemplate captures a running session's windows and panes into a .p
+// template, by default in the session's directory.
+func saveTemplate(sessionName, path string, withCommands, force bool) error {
+	layout, err := tmux.CaptureLayout(sessionName, withCommands)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		dir, err := tmux.GetSessionPath(sessionName)
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(dir, template.FileName)
+	} else {
+		if path, err = resolvePath(path); err != nil {
+			return err
+		}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			path = filepath.Join(path, template.FileName)
+		}
+	}
+	tpl := template.FromLayout(sessionName, layout, filepath.Dir(path))
+	if err := template.Save(path, tpl, force); err != nil {
+		return err
+	}
+	// The template was captured from the user's own session.
+	if err := template.Trust(path); err != nil {
+		return err
+	}
+	fmt.Printf(i18n.MsgSavedTemplateFmt, path)
+	return nil
+}
+
+// openRemote clones remote unless its directory already exists, then
+// creates a session for it like p <path>. path is the argument it was
+// given as. A fresh clone starts without the commands of its template or
+// detected layout. An owner/repo shorthand is only cloned after asking, as
+// it may be a mistyped path.
+func openRemote(remote git.Remote, path, overrideName string, opts createOptions, dry bool) error {
+	dir, err := git.CloneDir(remote)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		if dry {
+			fmt.Printf(i18n.MsgPlanCloneFmt, remote.URL, dir)
+			return nil
+		}
+		if remote.Shorthand && !confirmClone(remote, dir) {
+			// Report it as the path it may have been meant as.
+			_, err := buildSessionSpec(path, overrideName, false)
+			return err
+		}
+		if err := git.Clone(remote.URL, dir); err != nil {
+			return err
+		}
+		opts.untrusted = true
+	} else if err != nil {
+		return err
+	}
+	if dry {
+		return dryRun(dir, overrideName, opts)
+	}
+	return createSessionFromPath(dir, overrideName, opts)
+}
+
+// confirmClone asks whether to clone a shorthand remote. Without a
+// terminal to ask on, it doesn't.
+func confirmClone(remote git.Remote, dir string) bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+	fmt.Fprintf(os.Stderr, i18n.MsgConfirmCloneFmt, remote.URL, dir)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// startsLocally reports whether the first element of a relative path
+// exists, in which case an owner/repo argument is taken as a path.
+func startsLocally(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	_, err := os.Stat(first)
+	return err == nil
+}
+
+// openWorktree opens a session for branch of the repository p runs in,
+// named {repo}-{branch}. The branch's worktree is reused when it has one,
+// otherwise created.
+func openWorktree(branch string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	mainWorktree, err := git.MainWorktree(cwd)
+	if err != nil {
+		return fmt.Errorf(i18n.ErrWorktreeOutsideRepoFmt, err)
+	}
+	repo, err := git.RepoName(cwd)
+	if err != nil {
+		return err
+	}
+	w, found, err := git.FindWorktree(cwd, branch)
+	if err != nil {
+		return err
+	}
+	path := w.Path
+	if !found {
+		path = git.WorktreeDir(mainWorktree, repo, branch)
+		if err := git.AddWorktree(mainWorktree, path, branch); err != nil {
+			return err
+		}
+	}
+	name, err := naming.Expand("{repo}-{branch}", path)
+	if err != nil {
+		return err
+	}
+	return createSessionFromPath(path, name, createOptions{template: true})
+}
+
+// removeWorktree removes branch's worktree and kills the sessions rooted in
+// it. The branch itself is kept.
+func removeWorktree(branch string, force bool) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	mainWorktree, err := git.MainWorktree(cwd)
+	if err != nil {
+		return fmt.Errorf(i18n.ErrWorktreeRemoveOutsideRepoFmt, err)
+	}
+	w, found, err := git.FindWorktree(cwd, branch)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf(i18n.ErrNoWorktreeForBranchFmt, branch)
+	}
+	if w.Path == mainWorktree {
+		return fmt.Errorf(i18n.ErrMainWorktreeCheckoutFmt, branch)
+	}
+	sessions, err := tmux.SessionsInDirectory(w.Path)
+	if err != nil {
+		return err
+	}
+	if err := git.RemoveWorktree(mainWorktree, w.Path, force); err != nil {
+		if force {
+			return err
+		}
+		return fmt.Errorf(i18n.ErrWorktreeHasChangesFmt, err)
+	}
+	fmt.Printf(i18n.MsgRemovedWorktreeFmt, w.Path)
+
+	// Killing the session p runs in ends p, so it goes last.
+	current, _ := tmux.CurrentSession()
+	killCurrent := false
+	for _, name := range sessions {
+		if name == current {
+			killCurrent = true
+			continue
+		}
+		if err := tmux.KillSession(name); err != nil {
+			return err
+		}
+		fmt.Printf(i18n.MsgKilledSessionFmt, name)
+	}
+	if killCurrent {
+		fmt.Printf(i18n.MsgKillingCurrentSessionFmt, current)
+		return tmux.KillSession(current)
+	}
+	return nil
+}
+
+// takeSnapshot saves every session on the server for restoreSnapshot.
+func takeSnapshot(scrollback bool) error {
+	snap, err := snapshot.Take(scrollback)
+	if err != nil {
+		return err
+	}
+	path, err := snapshot.Save(snap)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(i18n.MsgSavedSnapshotFmt, len(snap.Sessions), path)
+	return nil
+}
+
+// restoreSnapshot rebuilds the sessions of a snapshot, by default the
+// latest, skipping those already running.
+func restoreSnapshot(path string) error {
+	var err error
+	if path == "" {
+		path, err = snapshot.Latest()
+	} else {
+		path, err = resolvePath(path)
+	}
+	if err != nil {
+		return err
+	}
+	snap, err := snapshot.Load(path)
+	if err != nil {
+		return err
+	}
+	results, err := snapshot.Restore(snap)
+	if err != nil {
+		return err
+	}
+	var failed []error
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed = append(failed, fmt.Errorf("%s: %w", r.Session, r.Err))
+		case r.Skipped:
+			fmt.Printf(i18n.MsgSessionAlreadyRunningFmt, r.Session)
+		default:
+			fmt.Printf(i18n.MsgSessionRestoredFmt, r.Session)
+		}
+	}
+	return errors.Join(failed...)
 }
 
 type sessionSpec struct {
 	sessionName string
 	workingDir  string
+	// startDir is where the first pane starts when it differs from
+	// workingDir, such as the subdirectory p was run from with --root.
+	startDir string
 }
 
-func buildSessionSpec(path, overrideName string) (*sessionSpec, error) {
+// buildSessionSpec resolves the session's directory and name. With root,
+// the directory is the project containing path, and path is kept as the
+// directory the first pane starts in.
+func buildSessionSpec(path, overrideName string, root bool) (*sessionSpec, error) {
 	if path == "" {
 		return nil, fmt.Errorf("path is required")
 	}
@@ -156,13 +880,43 @@ func buildSessionSpec(path, overrideName string) (*sessionSpec, error) {
 	if !info.IsDir() {
 		return nil, fmt.Errorf("not a directory: %s", resolved)
 	}
+	var startDir string
+	if root {
+		if project := project.Root(resolved); project != resolved {
+			resolved, startDir = project, resolved
+		}
+	}
 	sessionName := overrideName
 	if sessionName == "" {
+		sessionName = strings.TrimSpace(os.Getenv(envNameTemplate))
+	}
+	switch {
+	case sessionName == "":
 		sessionName = filepath.Base(resolved)
+	case strings.Contains(sessionName, "{"):
+		if sessionName, err = naming.Expand(sessionName, resolved); err != nil {
+			return nil, err
+		}
+	}
+	return &sessionSpec{sessionName: sessionName, workingDir: resolved, startDir: startDir}, nil
+}
+
+// startIn makes the first pane of a new session start in dir, unless the
+// layout already gives it a directory.
+func startIn(layout tmux.Layout, dir string) tmux.Layout {
+	if dir == "" || len(layout.Windows) == 0 || len(layout.Windows[0].Panes) == 0 || layout.Windows[0].Panes[0].Dir != "" {
+		return layout
 	}
-	return &sessionSpec{sessionName: sessionName, workingDir: resolved}, nil
+	windows := slices.Clone(layout.Windows)
+	windows[0].Panes = slices.Clone(windows[0].Panes)
+	windows[0].Panes[0].Dir = dir
+	layout.Windows = windows
+	return layout
 }
 
+// envNameTemplate is the default naming template for new sessions.
+const envNameTemplate = "P_NAME_TEMPLATE"
+
 func resolvePath(path string) (string, error) {
 	switch {
 	case path == ".":
@@ -178,19 +932,38 @@ func resolvePath(path string) (string, error) {
 	}
 }
 
-func attachAndLog(sessionName string, action history.Action) error {
-	targetDir, err := tmux.GetSessionPath(sessionName)
-	if err != nil {
-		targetDir = ""
+func attachAndLog(target tmux.Target, action history.Action) error {
+	// A session whose directory can't be found can't be relaunched, so it
+	// is not logged.
+	if targetDir, err := sessionDir(target); err == nil && targetDir != "" {
+		logLaunch(action, target.Session, targetDir, target.Server)
 	}
-	logLaunch(action, sessionName, targetDir)
-	return tmux.AttachToSession(sessionName)
+	return tmux.AttachToSession(target)
 }
 
-func logLaunch(action history.Action, sessionName, targetDir string) {
+// sessionDir returns the directory of target's session, looked up on the
+// server the session is on.
+func sessionDir(target tmux.Target) (dir string, err error) {
+	s := target.Server
+	if s == (tmux.Server{}) {
+		s = tmux.CurrentServer()
+	}
+	err = tmux.WithServer(s, func() error {
+		dir, err = tmux.GetSessionPath(target.Session)
+		return err
+	})
+	return dir, err
+}
+
+// logLaunch records a launch in the history. A zero server means the
+// selected one.
+func logLaunch(action history.Action, sessionName, targetDir string, server tmux.Server) {
 	if sessionName == "" {
 		return
 	}
+	if server == (tmux.Server{}) {
+		server = tmux.CurrentServer()
+	}
 	invokeDir, _ := os.Getwd()
 	if targetDir != "" {
 		abs, err := filepath.Abs(targetDir)
@@ -205,6 +978,9 @@ func logLaunch(action history.Action, sessionName, targetDir string) {
 		InvokeDir:   invokeDir,
 		TargetDir:   targetDir,
 	}
+	if server != (tmux.Server{}) {
+		entry.Server = server.String()
+	}
 	if err := history.Append(entry); err != nil {
 		fmt.Fprintf(os.Stderr, i18n.WarnWriteHistoryFailedFmt, err)
 	}
@@ -215,7 +991,15 @@ type commandKind int
 const (
 	commandSelector commandKind = iota
 	commandCreate
+	commandClone
+	commandAttach
 	commandHistory
+	commandUnstyle
+	commandSaveTemplate
+	commandSnapshot
+	commandRestore
+	commandWorktree
+	commandWorktreeRemove
 	commandVersion
 	commandHelp
 )
@@ -224,9 +1008,138 @@ type command struct {
 	kind        commandKind
 	path        string
 	sessionName string
+	target      tmux.Target
+	servers     []tmux.Server
+	reconfigure bool
+	noTemplate  bool
+	dryRun      bool
+	root        bool
+	// remote is the repository to clone for commandClone.
+	remote git.Remote
+	// branch is the branch of --worktree and --worktree-remove.
+	branch string
+	// withCommands applies to --save-template, force to --save-template
+	// and --worktree-remove.
+	withCommands bool
+	force        bool
+	scrollback   bool
 }
 
+// targetPrefix marks an argument as a tmux target instead of a path.
+const targetPrefix = "@"
+
 func parseArgs(args []string) (*command, error) {
+	args, servers, err := extractServerFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	args, reconfigure := extractFlag(args, "--reconfigure")
+	args, noTemplate := extractFlag(args, "--no-template")
+	args, dryRun := extractFlag(args, "--dry-run")
+	args, root := extractFlag(args, "--root")
+	cmd, err := parseCommand(args)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun && cmd.kind != commandCreate && cmd.kind != commandClone {
+		return nil, errors.New("--dry-run only applies to p <path> and p <url>")
+	}
+	cmd.dryRun = dryRun
+	cmd.root = root
+	cmd.servers = servers
+	cmd.reconfigure = reconfigure
+	cmd.noTemplate = noTemplate
+	return cmd, nil
+}
+
+func (c *command) createOptions() createOptions {
+	return createOptions{template: !c.noTemplate, root: c.root || envEnabled(envRoot)}
+}
+
+// parseSaveTemplate parses the arguments of --save-template:
+// <session> [path] [--commands] [--force].
+func parseSaveTemplate(args []string) (*command, error) {
+	cmd := &command{kind: commandSaveTemplate}
+	var operands []string
+	for _, arg := range args {
+		switch {
+		case arg == "--commands":
+			cmd.withCommands = true
+		case arg == "--force":
+			cmd.force = true
+		case strings.HasPrefix(arg, "--"):
+			return nil, fmt.Errorf("unknown option: %s", arg)
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	if len(operands) == 0 || len(operands) > 2 || strings.TrimSpace(operands[0]) == "" {
+		return nil, errors.New("--save-template requires a session name and an optional path")
+	}
+	cmd.sessionName = operands[0]
+	if len(operands) == 2 {
+		cmd.path = operands[1]
+	}
+	return cmd, nil
+}
+
+// extractFlag removes every occurrence of a boolean flag from args and
+// reports whether it was present.
+func extractFlag(args []string, name string) ([]string, bool) {
+	var rest []string
+	found := false
+	for _, arg := range args {
+		if arg == name {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
+// extractServerFlags pulls --socket/-L/-S out of args, wherever they appear.
+func extractServerFlags(args []string) ([]string, []tmux.Server, error) {
+	var rest []string
+	var servers []tmux.Server
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var value string
+		switch {
+		case strings.HasPrefix(arg, "--socket="):
+			value = strings.TrimPrefix(arg, "--socket=")
+		case arg == "--socket" || arg == "-L" || arg == "-S":
+			if i+1 >= len(args) {
+				return nil, nil, fmt.Errorf("%s requires a value", arg)
+			}
+			value = args[i+1]
+			i++
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		if strings.TrimSpace(value) == "" {
+			return nil, nil, fmt.Errorf("%s requires a value", arg)
+		}
+		var s tmux.Server
+		var err error
+		switch arg {
+		case "-L":
+			s = tmux.Server{Name: value}
+		case "-S":
+			s.Socket, err = filepath.Abs(value)
+		default:
+			s, err = tmux.ParseServer(value)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		servers = append(servers, s)
+	}
+	return rest, servers, nil
+}
+
+func parseCommand(args []string) (*command, error) {
 	if len(args) == 0 {
 		return &command{kind: commandSelector}, nil
 	}
@@ -240,9 +1153,72 @@ func parseArgs(args []string) (*command, error) {
 			return nil, fmt.Errorf("--log cannot be combined with other arguments")
 		}
 		return &command{kind: commandHistory}, nil
+	case "--unstyle":
+		if len(args) != 2 || strings.TrimSpace(args[1]) == "" {
+			return nil, errors.New("--unstyle requires a session name")
+		}
+		return &command{kind: commandUnstyle, sessionName: args[1]}, nil
+	case "--save-template":
+		return parseSaveTemplate(args[1:])
+	case "--worktree":
+		if len(args) != 2 || strings.TrimSpace(args[1]) == "" {
+			return nil, errors.New("--worktree requires a branch name")
+		}
+		return &command{kind: commandWorktree, branch: args[1]}, nil
+	case "--worktree-remove":
+		cmd := &command{kind: commandWorktreeRemove}
+		for _, arg := range args[1:] {
+			switch {
+			case arg == "--force":
+				cmd.force = true
+			case strings.HasPrefix(arg, "--"):
+				return nil, fmt.Errorf("unknown option: %s", arg)
+			case cmd.branch != "":
+				return nil, errors.New("--worktree-remove takes one branch name")
+			default:
+				cmd.branch = arg
+			}
+		}
+		if strings.TrimSpace(cmd.branch) == "" {
+			return nil, errors.New("--worktree-remove requires a branch name")
+		}
+		return cmd, nil
+	case "--snapshot":
+		cmd := &command{kind: commandSnapshot}
+		for _, arg := range args[1:] {
+			if arg != "--scrollback" {
+				return nil, fmt.Errorf("unknown option: %s", arg)
+			}
+			cmd.scrollback = true
+		}
+		return cmd, nil
+	case "--restore":
+		if len(args) > 2 {
+			return nil, errors.New("--restore takes at most one snapshot file")
+		}
+		cmd := &command{kind: commandRestore}
+		if len(args) == 2 {
+			cmd.path = args[1]
+		}
+		return cmd, nil
+	}
+
+	if strings.HasPrefix(args[0], targetPrefix) {
+		if len(args) > 1 {
+			return nil, fmt.Errorf("%s cannot be combined with other arguments", args[0])
+		}
+		target, err := tmux.ParseTarget(strings.TrimPrefix(args[0], targetPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return &command{kind: commandAttach, target: target}, nil
 	}
 
 	cmd := &command{kind: commandCreate, path: args[0]}
+	if remote, ok := git.ParseRemote(args[0], !startsLocally(args[0])); ok {
+		cmd.kind = commandClone
+		cmd.remote = remote
+	}
 	for i := 1; i < len(args); i++ {
 		arg := args[i]
 		if strings.HasPrefix(arg, "--name=") {
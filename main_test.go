@@ -1,6 +1,19 @@
 package main
 
-import "testing"
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wilmoore/p/internal/git"
+	"github.com/wilmoore/p/internal/template"
+	"github.com/wilmoore/p/internal/tmux"
+	"golang.org/x/term"
+)
 
 func TestParseArgs(t *testing.T) {
 	tests := []struct {
@@ -46,3 +59,557 @@ func TestParseArgs(t *testing.T) {
 		})
 	}
 }
+
+func TestParseArgsTarget(t *testing.T) {
+	cmd, err := parseArgs([]string{"@api:logs.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.kind != commandAttach {
+		t.Fatalf("kind mismatch: got %v want %v", cmd.kind, commandAttach)
+	}
+	if cmd.target.Session != "api" || cmd.target.Window != "logs" || cmd.target.Pane != "1" {
+		t.Fatalf("target mismatch: got %+v", cmd.target)
+	}
+
+	for _, args := range [][]string{{"@"}, {"@api:"}, {"@api", "--name", "x"}} {
+		if _, err := parseArgs(args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
+
+func TestParseArgsServers(t *testing.T) {
+	cmd, err := parseArgs([]string{"--socket", "work", "proj", "-S", "/tmp/pair.sock", "--socket=/tmp/x/s", "--name", "n"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.kind != commandCreate || cmd.path != "proj" || cmd.sessionName != "n" {
+		t.Fatalf("command mismatch: %+v", cmd)
+	}
+	want := []tmux.Server{{Name: "work"}, {Socket: "/tmp/pair.sock"}, {Socket: "/tmp/x/s"}}
+	if !reflect.DeepEqual(cmd.servers, want) {
+		t.Fatalf("servers: got %+v want %+v", cmd.servers, want)
+	}
+
+	cmd, err = parseArgs([]string{"-L", "personal"})
+	if err != nil || cmd.kind != commandSelector {
+		t.Fatalf("expected selector with socket, got %+v, %v", cmd, err)
+	}
+
+	for _, args := range [][]string{{"--socket"}, {"-L", ""}, {"--socket="}} {
+		if _, err := parseArgs(args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
+
+func TestParseArgsReconfigure(t *testing.T) {
+	cmd, err := parseArgs([]string{"@api", "--reconfigure"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.kind != commandAttach || !cmd.reconfigure {
+		t.Fatalf("command mismatch: %+v", cmd)
+	}
+	if cmd, _ := parseArgs([]string{"@api"}); cmd.reconfigure {
+		t.Fatalf("reconfigure should default to off")
+	}
+}
+
+func TestParseArgsUnstyle(t *testing.T) {
+	cmd, err := parseArgs([]string{"--unstyle", "api"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.kind != commandUnstyle || cmd.sessionName != "api" {
+		t.Fatalf("command mismatch: %+v", cmd)
+	}
+	for _, args := range [][]string{{"--unstyle"}, {"--unstyle", "a", "b"}} {
+		if _, err := parseArgs(args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
+
+func TestParseArgsNoTemplate(t *testing.T) {
+	cmd, err := parseArgs([]string{".", "--no-template", "--name", "api"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.kind != commandCreate || !cmd.noTemplate || cmd.sessionName != "api" {
+		t.Fatalf("command mismatch: %+v", cmd)
+	}
+}
+
+func TestSessionLayoutUsesTemplate(t *testing.T) {
+	dir := t.TempDir()
+	content := "name = tpl\n[window editor]\n[window logs]\n"
+	if err := os.WriteFile(filepath.Join(dir, template.FileName), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("P_WINDOWS", "one")
+
+	spec := &sessionSpec{sessionName: "dir", workingDir: dir}
+	layout, _, err := sessionLayout(spec, "", createOptions{template: true}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spec.sessionName != "tpl" || len(layout.Windows) != 2 || layout.Windows[0].Name != "editor" {
+		t.Fatalf("expected the template's session, got %q %+v", spec.sessionName, layout)
+	}
+
+	spec = &sessionSpec{sessionName: "custom", workingDir: dir}
+	if _, _, err := sessionLayout(spec, "custom", createOptions{template: true}, false); err != nil || spec.sessionName != "custom" {
+		t.Fatalf("--name should win over the template, got %q, %v", spec.sessionName, err)
+	}
+
+	spec = &sessionSpec{sessionName: "dir", workingDir: dir}
+	layout, _, err = sessionLayout(spec, "", createOptions{}, false)
+	if err != nil || spec.sessionName != "dir" || len(layout.Windows) != 1 || layout.Windows[0].Name != "one" {
+		t.Fatalf("expected P_WINDOWS without the template, got %q %+v, %v", spec.sessionName, layout, err)
+	}
+}
+
+func TestParseArgsDryRun(t *testing.T) {
+	cmd, err := parseArgs([]string{"--dry-run", "."})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.kind != commandCreate || !cmd.dryRun {
+		t.Fatalf("command mismatch: %+v", cmd)
+	}
+	if _, err := parseArgs([]string{"@api", "--dry-run"}); err == nil {
+		t.Fatalf("expected --dry-run to require a path")
+	}
+}
+
+func TestSessionLayoutDetection(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("P_WINDOWS", "")
+	t.Setenv("P_TRUST_PATH", filepath.Join(t.TempDir(), "trusted"))
+
+	spec := &sessionSpec{sessionName: "x", workingDir: dir}
+	if _, source, err := sessionLayout(spec, "", createOptions{template: true}, false); err != nil || source != "P_WINDOWS" {
+		t.Fatalf("detection should be opt-in, got %q, %v", source, err)
+	}
+
+	t.Setenv("P_DETECT", "1")
+	layout, source, err := sessionLayout(spec, "", createOptions{template: true}, false)
+	if err != nil || !strings.HasPrefix(source, "detected from go.mod") || layout.Windows[0].Name != "editor" {
+		t.Fatalf("expected a detected layout, got %q %+v, %v", source, layout, err)
+	}
+	if _, source, _ := sessionLayout(spec, "", createOptions{}, false); source != "P_WINDOWS" {
+		t.Fatalf("--no-template should skip detection, got %q", source)
+	}
+}
+
+func TestWritePlan(t *testing.T) {
+	layout := tmux.Layout{
+		Focus: "logs",
+		Env:   map[string]string{"PORT": "8080"},
+		Windows: []tmux.WindowSpec{
+			{Name: "editor", Panes: []tmux.PaneSpec{{Command: "nvim ."}}},
+			{Name: "logs", Arrangement: "tiled", Panes: []tmux.PaneSpec{{}, {Dir: "/srv/log", Command: "tail -f x"}}},
+		},
+	}
+	var out strings.Builder
+	writePlan(&out, &sessionSpec{sessionName: "api", workingDir: "/work/api"}, layout, "P_WINDOWS")
+	want := `session:   api
+directory: /work/api
+layout:    P_WINDOWS
+env:       PORT=8080
+  editor
+    .: nvim .
+* logs @tiled
+    .: (shell)
+    /srv/log: tail -f x
+`
+	if out.String() != want {
+		t.Fatalf("plan:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestParseArgsSaveTemplate(t *testing.T) {
+	cmd, err := parseArgs([]string{"--save-template", "api", "--commands", "out/.p"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.kind != commandSaveTemplate || cmd.sessionName != "api" || cmd.path != "out/.p" || !cmd.withCommands || cmd.force {
+		t.Fatalf("command mismatch: %+v", cmd)
+	}
+	for _, args := range [][]string{{"--save-template"}, {"--save-template", "a", "b", "c"}, {"--save-template", "a", "--all"}} {
+		if _, err := parseArgs(args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
+
+func TestParseArgsSnapshotRestore(t *testing.T) {
+	cmd, err := parseArgs([]string{"--snapshot", "--scrollback"})
+	if err != nil || cmd.kind != commandSnapshot || !cmd.scrollback {
+		t.Fatalf("snapshot: %+v, %v", cmd, err)
+	}
+	cmd, err = parseArgs([]string{"--restore", "old.json"})
+	if err != nil || cmd.kind != commandRestore || cmd.path != "old.json" {
+		t.Fatalf("restore: %+v, %v", cmd, err)
+	}
+	for _, args := range [][]string{{"--snapshot", "--all"}, {"--restore", "a", "b"}} {
+		if _, err := parseArgs(args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
+
+func TestPromptSessionName(t *testing.T) {
+	// An unused socket: every name is available.
+	restore := tmux.SetServer(tmux.Server{Socket: filepath.Join(t.TempDir(), "none")})
+	defer restore()
+
+	collision := &tmux.NameCollisionError{Name: "api", Dir: "/personal/api", Existing: "/work/api", Suggestion: "api-1a2b3c"}
+	tests := map[string]string{
+		"\n":          "api-1a2b3c",
+		"my.api\n":    "my_api",
+		"  other  \n": "other",
+	}
+	for input, want := range tests {
+		var out strings.Builder
+		got, err := promptSessionName(strings.NewReader(input), &out, collision)
+		if err != nil || got != want {
+			t.Fatalf("input %q: got %q, %v; want %q", input, got, err, want)
+		}
+		if !strings.Contains(out.String(), "/work/api") {
+			t.Fatalf("prompt should name the existing session's directory: %q", out.String())
+		}
+	}
+	if _, err := promptSessionName(strings.NewReader(""), io.Discard, collision); err != collision {
+		t.Fatalf("expected the collision on EOF, got %v", err)
+	}
+}
+
+func TestNameSessionSanitizesExplicitNames(t *testing.T) {
+	spec := &sessionSpec{sessionName: "example.com:8080", workingDir: "/work"}
+	if err := nameSession(spec, true, false); err != nil || spec.sessionName != "example_com_8080" {
+		t.Fatalf("got %q, %v", spec.sessionName, err)
+	}
+}
+
+func TestBuildSessionSpecNameTemplate(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("P_NAME_TEMPLATE", "dev-{dir}")
+	spec, err := buildSessionSpec(dir, "", false)
+	if err != nil || spec.sessionName != "dev-"+filepath.Base(dir) {
+		t.Fatalf("P_NAME_TEMPLATE: %+v, %v", spec, err)
+	}
+	spec, err = buildSessionSpec(dir, "{parent}", false)
+	if err != nil || spec.sessionName != filepath.Base(filepath.Dir(dir)) {
+		t.Fatalf("--name template: %+v, %v", spec, err)
+	}
+	spec, err = buildSessionSpec(dir, "literal", false)
+	if err != nil || spec.sessionName != "literal" {
+		t.Fatalf("literal --name: %+v, %v", spec, err)
+	}
+}
+
+func TestBuildSessionSpecRoot(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "repo")
+	sub := filepath.Join(repo, "ui")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "package.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("P_ROOT_MARKERS", "")
+	t.Setenv("P_NAME_TEMPLATE", "")
+
+	spec, err := buildSessionSpec(sub, "", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &sessionSpec{sessionName: "repo", workingDir: repo, startDir: sub}
+	if !reflect.DeepEqual(spec, want) {
+		t.Fatalf("spec = %+v, want %+v", spec, want)
+	}
+	if spec, _ := buildSessionSpec(sub, "", false); spec.workingDir != sub || spec.startDir != "" {
+		t.Fatalf("without --root the directory is kept: %+v", spec)
+	}
+
+	layout := startIn(tmux.Layout{Windows: []tmux.WindowSpec{{Name: "home", Panes: []tmux.PaneSpec{{}}}}}, sub)
+	if layout.Windows[0].Panes[0].Dir != sub {
+		t.Fatalf("first pane should start in %s: %+v", sub, layout)
+	}
+}
+
+func TestParseArgsRoot(t *testing.T) {
+	t.Setenv("P_ROOT", "")
+	cmd, err := parseArgs([]string{".", "--root"})
+	if err != nil || !cmd.createOptions().root {
+		t.Fatalf("--root: %+v, %v", cmd, err)
+	}
+	cmd, _ = parseArgs([]string{"."})
+	if cmd.createOptions().root {
+		t.Fatalf("root mode should be off by default")
+	}
+	t.Setenv("P_ROOT", "1")
+	if !cmd.createOptions().root {
+		t.Fatalf("P_ROOT=1 should enable root mode")
+	}
+}
+
+func TestParseArgsWorktree(t *testing.T) {
+	cmd, err := parseArgs([]string{"--worktree", "feature/login"})
+	if err != nil || cmd.kind != commandWorktree || cmd.branch != "feature/login" {
+		t.Fatalf("--worktree: %+v, %v", cmd, err)
+	}
+	cmd, err = parseArgs([]string{"--worktree-remove", "--force", "feature/login"})
+	if err != nil || cmd.kind != commandWorktreeRemove || cmd.branch != "feature/login" || !cmd.force {
+		t.Fatalf("--worktree-remove: %+v, %v", cmd, err)
+	}
+	for _, args := range [][]string{{"--worktree"}, {"--worktree", "a", "b"}, {"--worktree-remove"}, {"--worktree-remove", "a", "b"}} {
+		if _, err := parseArgs(args); err == nil {
+			t.Fatalf("expected error for %v", args)
+		}
+	}
+}
+
+func TestParseArgsRemote(t *testing.T) {
+	cmd, err := parseArgs([]string{"git@github.com:wilmoore/p.git", "--name", "p"})
+	if err != nil || cmd.kind != commandClone || cmd.remote.Path != "wilmoore/p" || cmd.sessionName != "p" {
+		t.Fatalf("scp address: %+v, %v", cmd, err)
+	}
+	cmd, err = parseArgs([]string{"wilmoore/p", "--dry-run"})
+	if err != nil || cmd.kind != commandClone || cmd.remote.URL != "https://github.com/wilmoore/p.git" || !cmd.dryRun {
+		t.Fatalf("shorthand: %+v, %v", cmd, err)
+	}
+
+	// An existing owner/repo directory is a path, not a shorthand.
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(filepath.Join("wilmoore", "p"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd, err = parseArgs([]string{"wilmoore/p"})
+	if err != nil || cmd.kind != commandCreate || cmd.path != "wilmoore/p" {
+		t.Fatalf("existing path: %+v, %v", cmd, err)
+	}
+	// So is a mistyped path under an existing directory.
+	cmd, err = parseArgs([]string{"wilmoore/typo"})
+	if err != nil || cmd.kind != commandCreate {
+		t.Fatalf("mistyped path: %+v, %v", cmd, err)
+	}
+	cmd, err = parseArgs([]string{"nosuchdir/typo"})
+	if err != nil || cmd.kind != commandClone || !cmd.remote.Shorthand {
+		t.Fatalf("shorthand: %+v, %v", cmd, err)
+	}
+}
+
+func TestOpenRemoteDryRun(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	src := filepath.Join(t.TempDir(), "app")
+	bare := filepath.Join(t.TempDir(), "app.git")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main", src},
+		{"-C", src, "-c", "user.name=p", "-c", "user.email=p@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+		{"clone", "-q", "--bare", src, bare},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	root := t.TempDir()
+	t.Setenv("P_SRC_ROOT", root)
+	remote, ok := git.ParseRemote("file://"+bare, false)
+	if !ok {
+		t.Fatal("file URL not recognised")
+	}
+	dir, _ := git.CloneDir(remote)
+	// An unused socket: no sessions are open.
+	restore := tmux.SetServer(tmux.Server{Socket: filepath.Join(t.TempDir(), "none")})
+	defer restore()
+
+	// A dry run clones nothing.
+	if err := openRemote(remote, "", "", createOptions{}, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("dry run created %s: %v", dir, err)
+	}
+
+	// An existing clone is used as is.
+	if err := git.Clone(remote.URL, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := openRemote(remote, "", "", createOptions{}, true); err != nil {
+		t.Fatalf("existing clone: %v", err)
+	}
+}
+
+func TestSessionDirLooksUpTheSessionsServer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping tmux integration test in short mode")
+	}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux not installed")
+	}
+	socketDir, err := os.MkdirTemp("", "p-tmux-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TMUX", "")
+	other := tmux.Server{Socket: filepath.Join(socketDir, "other")}
+	// The selected server has no sessions.
+	restore := tmux.SetServer(tmux.Server{Socket: filepath.Join(socketDir, "none")})
+	t.Cleanup(func() {
+		exec.Command("tmux", "-S", other.Socket, "kill-server").Run()
+		restore()
+		os.RemoveAll(socketDir)
+	})
+
+	dir := t.TempDir()
+	if out, err := exec.Command("tmux", "-S", other.Socket, "new-session", "-d", "-s", "api", "-c", dir).CombinedOutput(); err != nil {
+		t.Fatalf("new-session: %v\n%s", err, out)
+	}
+	got, err := sessionDir(tmux.Target{Session: "api", Server: other})
+	if err != nil || got != dir {
+		t.Fatalf("sessionDir on other server = %q, %v; want %q", got, err, dir)
+	}
+	if _, err := sessionDir(tmux.Target{Session: "api"}); err == nil {
+		t.Fatalf("expected no session api on the selected server")
+	}
+}
+
+func TestRunVersionAndHelpIgnoreConfigErrors(t *testing.T) {
+	t.Setenv("P_THEME", "bogus")
+	t.Setenv("P_COLLISION", "x")
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	for _, flag := range []string{"--version", "--help"} {
+		os.Args = []string{"p", flag}
+		if err := run(); err != nil {
+			t.Fatalf("p %s: %v", flag, err)
+		}
+	}
+	if err := checkConfig(); err == nil {
+		t.Fatalf("expected P_THEME=bogus to be reported")
+	}
+}
+
+func TestOpenRemoteShorthandNeedsConfirmation(t *testing.T) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		t.Skip("stdin is a terminal")
+	}
+	root := t.TempDir()
+	t.Setenv("P_SRC_ROOT", root)
+	t.Chdir(t.TempDir())
+	remote, _ := git.ParseRemote("nosuchdir/typo", true)
+
+	err := openRemote(remote, "nosuchdir/typo", "", createOptions{}, false)
+	if err == nil || !strings.Contains(err.Error(), "directory does not exist") {
+		t.Fatalf("expected the path error, got %v", err)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Fatalf("expected nothing cloned, got %v", entries)
+	}
+}
+
+func TestSessionLayoutTrust(t *testing.T) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		t.Skip("stdin is a terminal")
+	}
+	t.Setenv("P_TRUST_PATH", filepath.Join(t.TempDir(), "trusted"))
+	t.Setenv("P_WINDOWS", "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, template.FileName)
+	content := "[env]\nPORT = 1\n[window dev]\ncommand = make run\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec := &sessionSpec{sessionName: "app", workingDir: dir}
+
+	// A dry run shows the commands it would ask about.
+	layout, source, err := sessionLayout(spec, "", createOptions{template: true}, false)
+	if err != nil || layout.Windows[0].Panes[0].Command != "make run" || !strings.Contains(source, "not trusted") {
+		t.Fatalf("dry run: %+v, %q, %v", layout, source, err)
+	}
+	// Without a terminal to ask on, it runs none of them.
+	layout, _, err = sessionLayout(spec, "", createOptions{template: true}, true)
+	if err != nil || layout.Windows[0].Panes[0].Command != "" || len(layout.Env) != 0 {
+		t.Fatalf("untrusted: %+v, %v", layout, err)
+	}
+	// Nor does a fresh clone, whatever its template or detected layout.
+	layout, source, err = sessionLayout(spec, "", createOptions{template: true, untrusted: true}, false)
+	if err != nil || layout.Windows[0].Panes[0].Command != "" || !strings.Contains(source, "new clone") {
+		t.Fatalf("fresh clone: %+v, %v", layout, err)
+	}
+	// An approved template runs them.
+	if err := template.Trust(path); err != nil {
+		t.Fatal(err)
+	}
+	layout, _, err = sessionLayout(spec, "", createOptions{template: true}, true)
+	if err != nil || layout.Windows[0].Panes[0].Command != "make run" || layout.Env["PORT"] != "1" {
+		t.Fatalf("trusted: %+v, %v", layout, err)
+	}
+}
+
+func TestSessionLayoutTrustsDetectedLayouts(t *testing.T) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		t.Skip("stdin is a terminal")
+	}
+	t.Setenv("P_TRUST_PATH", filepath.Join(t.TempDir(), "trusted"))
+	t.Setenv("P_DETECT", "1")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec := &sessionSpec{sessionName: "app", workingDir: dir}
+	testCommand := func(layout tmux.Layout) string {
+		return layout.Windows[1].Panes[0].Command
+	}
+
+	layout, source, err := sessionLayout(spec, "", createOptions{template: true}, false)
+	if err != nil || testCommand(layout) != "go test ./..." || !strings.Contains(source, "not trusted") {
+		t.Fatalf("dry run: %+v, %q, %v", layout, source, err)
+	}
+	layout, _, err = sessionLayout(spec, "", createOptions{template: true}, true)
+	if err != nil || testCommand(layout) != "" {
+		t.Fatalf("untrusted: %+v, %v", layout, err)
+	}
+	tpl, _ := template.Detect(dir)
+	if err := template.TrustDetected(dir, tpl); err != nil {
+		t.Fatal(err)
+	}
+	layout, _, err = sessionLayout(spec, "", createOptions{template: true}, true)
+	if err != nil || testCommand(layout) != "go test ./..." {
+		t.Fatalf("trusted: %+v, %v", layout, err)
+	}
+}
+
+func TestPromptTrust(t *testing.T) {
+	tpl := &template.Template{
+		Env:     map[string]string{"PORT": "1"},
+		Windows: []tmux.WindowSpec{{Name: "dev", Panes: []tmux.PaneSpec{{Command: "make run"}}}},
+	}
+	for answer, want := range map[string]bool{"y\n": true, "yes\n": true, "\n": false, "n\n": false, "": false} {
+		var out strings.Builder
+		if got := promptTrust(strings.NewReader(answer), &out, "/src/app/.p", tpl); got != want {
+			t.Errorf("answer %q: got %v, want %v", answer, got, want)
+		}
+		if !strings.Contains(out.String(), "env PORT=1") || !strings.Contains(out.String(), "dev: make run") {
+			t.Errorf("expected the commands shown, got %q", out.String())
+		}
+	}
+}